@@ -0,0 +1,165 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"media-worker/database"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PastDays is how far back feeds keep aired episodes
+var PastDays = 30
+
+// MediaFeed holds every stored episode of one media
+func MediaFeed(ctx context.Context, q *database.Queries, mediaID int32) (Feed, error) {
+	events, err := loadEvents(ctx, q, database.ListCalendarEpisodesParams{
+		MediaID: pgtype.Int4{Int32: mediaID, Valid: true},
+	})
+	if err != nil {
+		return Feed{}, err
+	}
+
+	name := fmt.Sprintf("AniList %d", mediaID)
+	if len(events) > 0 && events[0].Title != "" {
+		name = events[0].Title
+	}
+	return Feed{Name: name, Events: events}, nil
+}
+
+// SeasonFeed holds the episodes of every media in a season, e.g. WINTER 2026
+func SeasonFeed(ctx context.Context, q *database.Queries, season string, year int32) (Feed, error) {
+	events, err := loadEvents(ctx, q, database.ListCalendarEpisodesParams{
+		Season:     pgtype.Text{String: season, Valid: true},
+		SeasonYear: pgtype.Int4{Int32: year, Valid: true},
+	})
+	if err != nil {
+		return Feed{}, err
+	}
+
+	return Feed{Name: fmt.Sprintf("%s %d", seasonName(season), year), Events: events}, nil
+}
+
+// WatchlistFeed holds the episodes of what a user is watching or planning to watch
+func WatchlistFeed(ctx context.Context, q *database.Queries, userID pgtype.UUID) (Feed, error) {
+	events, err := loadEvents(ctx, q, database.ListCalendarEpisodesParams{UserID: userID})
+	if err != nil {
+		return Feed{}, err
+	}
+
+	return Feed{Name: "Watchlist", Events: events}, nil
+}
+
+func loadEvents(ctx context.Context, q *database.Queries, params database.ListCalendarEpisodesParams) ([]Event, error) {
+	params.Since = since()
+
+	rows, err := q.ListCalendarEpisodes(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(rows))
+	for _, row := range rows {
+		event := Event{
+			MediaID:  row.MediaID,
+			Title:    row.Title,
+			Episode:  row.Episode,
+			Start:    time.Unix(row.AiringAt, 0).UTC(),
+			Sequence: row.RescheduleCount,
+			Modified: row.UpdatedAt.Time.UTC(),
+		}
+		if row.Duration.Valid {
+			event.Length = time.Duration(row.Duration.Int32) * time.Minute
+		}
+		if row.PreviousAiringAt.Valid {
+			event.Previous = time.Unix(row.PreviousAiringAt.Int64, 0).UTC()
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// WriteFeeds renders a feed per airing media, per season and per watchlist into dir as
+// media/<id>.ics, season/<year>-<season>.ics and watchlist/<user id>.ics
+func WriteFeeds(ctx context.Context, q *database.Queries, dir string) error {
+	mediaIds, err := q.ListCalendarMedia(ctx, since())
+	if err != nil {
+		return err
+	}
+	for _, id := range mediaIds {
+		feed, err := MediaFeed(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if err := writeFeed(filepath.Join(dir, "media", fmt.Sprintf("%d.ics", id)), feed); err != nil {
+			return err
+		}
+	}
+
+	seasons, err := q.ListCalendarSeasons(ctx, since())
+	if err != nil {
+		return err
+	}
+	for _, season := range seasons {
+		feed, err := SeasonFeed(ctx, q, season.Season.String, season.SeasonYear.Int32)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%d-%s.ics", season.SeasonYear.Int32, strings.ToLower(season.Season.String))
+		if err := writeFeed(filepath.Join(dir, "season", name), feed); err != nil {
+			return err
+		}
+	}
+
+	users, err := q.ListWatchlistUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		feed, err := WatchlistFeed(ctx, q, user)
+		if err != nil {
+			return err
+		}
+		if err := writeFeed(filepath.Join(dir, "watchlist", user.String()+".ics"), feed); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Wrote %d media, %d season and %d watchlist feeds to %s\n", len(mediaIds), len(seasons), len(users), dir)
+	return nil
+}
+
+// writeFeed replaces path through a rename so a subscriber never reads a half written file
+func writeFeed(path string, feed Feed) error {
+	var buf bytes.Buffer
+	if err := Render(&buf, feed); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func since() int64 {
+	return time.Now().AddDate(0, 0, -PastDays).Unix()
+}
+
+// seasonName turns AniList's WINTER into Winter
+func seasonName(season string) string {
+	if season == "" {
+		return season
+	}
+	return season[:1] + strings.ToLower(season[1:])
+}
@@ -0,0 +1,118 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// uidDomain scopes event UIDs so they never collide with another producer's
+const uidDomain = "taaampp"
+
+// DefaultEpisodeLength is used for media whose duration AniList does not know
+const DefaultEpisodeLength = 24 * time.Minute
+
+// Feed is one .ics file, events are written in the order given
+type Feed struct {
+	Name   string
+	Events []Event
+}
+
+// Event is a single airing episode
+type Event struct {
+	MediaID int32
+	Title   string
+	Episode int32
+	Start   time.Time
+	Length  time.Duration
+	// Previous is the earlier air time of a rescheduled episode, zero otherwise
+	Previous time.Time
+	// Sequence counts the reschedules, RFC 5545 wants it to go up whenever DTSTART changes
+	Sequence int32
+	// Modified is when the episode row last changed, it keeps the feed byte-identical between runs
+	Modified time.Time
+}
+
+// UID stays the same across renders and reschedules so calendar apps update the event in place
+func (e Event) UID() string {
+	return fmt.Sprintf("anilist-%d-episode-%d@%s", e.MediaID, e.Episode, uidDomain)
+}
+
+// Render writes feed as an RFC 5545 calendar with every timestamp in UTC
+func Render(w io.Writer, feed Feed) error {
+	bw := bufio.NewWriter(w)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//taaampp//media-worker//EN")
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	writeLine(bw, "X-WR-CALNAME:"+escapeText(feed.Name))
+
+	for _, event := range feed.Events {
+		length := event.Length
+		if length <= 0 {
+			length = DefaultEpisodeLength
+		}
+		summary := fmt.Sprintf("%s - Episode %d", event.Title, event.Episode)
+
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+event.UID())
+		writeLine(bw, "DTSTAMP:"+formatTime(event.Modified))
+		writeLine(bw, "LAST-MODIFIED:"+formatTime(event.Modified))
+		writeLine(bw, "DTSTART:"+formatTime(event.Start))
+		writeLine(bw, "DTEND:"+formatTime(event.Start.Add(length)))
+		writeLine(bw, "SUMMARY:"+escapeText(summary))
+		writeLine(bw, fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+		if !event.Previous.IsZero() {
+			writeLine(bw, "DESCRIPTION:"+escapeText("Rescheduled from "+event.Previous.UTC().Format(time.RFC1123)))
+		}
+		writeLine(bw, fmt.Sprintf("URL:https://anilist.co/anime/%d", event.MediaID))
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes a TEXT value, RFC 5545 section 3.3.11
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writeLine folds content lines longer than 75 octets without splitting a UTF-8 character,
+// RFC 5545 section 3.1. bufio.Writer keeps the first error so Render only checks Flush.
+func writeLine(w *bufio.Writer, line string) {
+	const limit = 75
+
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space which counts towards the limit
+		width = limit - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
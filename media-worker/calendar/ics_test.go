@@ -0,0 +1,132 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "short line",
+			line: "VERSION:2.0",
+			want: "VERSION:2.0\r\n",
+		},
+		{
+			name: "exactly 75 octets",
+			line: strings.Repeat("a", 75),
+			want: strings.Repeat("a", 75) + "\r\n",
+		},
+		{
+			name: "76 octets",
+			line: strings.Repeat("a", 76),
+			want: strings.Repeat("a", 75) + "\r\n a\r\n",
+		},
+		{
+			name: "continuation lines count the leading space",
+			line: strings.Repeat("a", 75+74+1),
+			want: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n",
+		},
+		{
+			name: "multi-byte character on the boundary moves to the next line",
+			line: "SUMMARY:" + strings.Repeat("a", 66) + "日本",
+			want: "SUMMARY:" + strings.Repeat("a", 66) + "\r\n 日本\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeLine(w, test.line)
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != test.want {
+				t.Errorf("writeLine(%q) = %q, want %q", test.line, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Frieren", "Frieren"},
+		{"Re:Zero, Season 3", `Re:Zero\, Season 3`},
+		{"a;b", `a\;b`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+		{"two\r\nlines", `two\nlines`},
+		{`\n`, `\\n`},
+	}
+
+	for _, test := range tests {
+		if got := escapeText(test.in); got != test.want {
+			t.Errorf("escapeText(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	start := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	event := Event{
+		MediaID:  154587,
+		Title:    "葬送のフリーレン, 第2期; " + strings.Repeat("長いタイトル", 5),
+		Episode:  3,
+		Start:    start,
+		Previous: start.Add(-7 * 24 * time.Hour),
+		Sequence: 1,
+		Modified: start.Add(-time.Hour),
+	}
+
+	var first, second bytes.Buffer
+	if err := Render(&first, Feed{Name: "Watchlist", Events: []Event{event}}); err != nil {
+		t.Fatal(err)
+	}
+	event.Start = event.Start.Add(time.Hour)
+	event.Sequence++
+	if err := Render(&second, Feed{Name: "Watchlist", Events: []Event{event}}); err != nil {
+		t.Fatal(err)
+	}
+
+	const uid = "UID:anilist-154587-episode-3@taaampp\r\n"
+	for _, out := range []string{first.String(), second.String()} {
+		if !strings.Contains(out, uid) {
+			t.Errorf("missing %q in\n%s", uid, out)
+		}
+	}
+
+	out := first.String()
+	if !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("output does not end with END:VCALENDAR and CRLF")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a UTF-8 character: %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	wantSummary := "SUMMARY:" + escapeText(event.Title) + " - Episode 3\r\n"
+	if !strings.Contains(unfolded, wantSummary) {
+		t.Errorf("unfolded output is missing %q", wantSummary)
+	}
+	if !strings.Contains(unfolded, "SEQUENCE:1\r\n") {
+		t.Errorf("unfolded output is missing SEQUENCE:1")
+	}
+}
@@ -54,6 +54,57 @@ func (ns NullMediaType) Value() (driver.Value, error) {
 	return string(ns.MediaType), nil
 }
 
+type AiringEpisode struct {
+	MediaID          int32
+	Episode          int32
+	AiringAt         int64
+	PreviousAiringAt pgtype.Int8
+	RescheduledAt    pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+	RescheduleCount  int32
+}
+
+type Character struct {
+	ID         int32
+	NameFull   pgtype.Text
+	NameNative pgtype.Text
+	Image      pgtype.Text
+}
+
+type CharacterVoiceActor struct {
+	MediaID     int32
+	CharacterID int32
+	StaffID     int32
+	Language    string
+}
+
+type CreditSync struct {
+	MediaID  int32
+	Credit   string
+	SyncedAt pgtype.Timestamptz
+}
+
+type FailedMedium struct {
+	MediaID       int32
+	Error         string
+	Attempts      int32
+	Payload       []byte
+	FirstFailedAt pgtype.Timestamptz
+	LastFailedAt  pgtype.Timestamptz
+}
+
+type Genre struct {
+	ID   int32
+	Name string
+}
+
+type MediaCharacter struct {
+	MediaID     int32
+	CharacterID int32
+	Role        string
+	Position    int32
+}
+
 type MediaDetail struct {
 	ID                int32
 	Description       pgtype.Text
@@ -72,23 +123,116 @@ type MediaDetail struct {
 	ScoreDistribution []string
 }
 
-type Medium struct {
-	ID           int32
-	Titles       string
-	Type         NullMediaType
-	Format       pgtype.Text
-	Status       string
-	Season       pgtype.Text
-	SeasonYear   pgtype.Int4
-	Episodes     pgtype.Int4
-	Chapters     pgtype.Int4
-	Volumes      pgtype.Int4
-	CoverImage   pgtype.Text
-	Genres       []string
+type MediaGenre struct {
+	MediaID int32
+	GenreID int32
+}
+
+type MediaHistory struct {
+	ID         int64
+	MediaID    int32
+	Field      string
+	OldValue   pgtype.Text
+	NewValue   pgtype.Text
+	ObservedAt pgtype.Timestamptz
+	SyncRunID  pgtype.Int8
+}
+
+type MediaRelation struct {
+	MediaID      int32
+	RelatedID    int32
+	RelationType string
+}
+
+type MediaSnapshot struct {
+	MediaID      int32
+	SnapshotDate pgtype.Date
 	AverageScore pgtype.Int4
-	Studios      []string
-	IsAdult      pgtype.Bool
-	LastUpdated  pgtype.Timestamptz
+	Popularity   int32
+	Trending     int32
+	Favourites   int32
+}
+
+type MediaStaff struct {
+	MediaID  int32
+	StaffID  int32
+	Role     string
+	Position int32
+}
+
+type MediaStudio struct {
+	MediaID  int32
+	StudioID int32
+}
+
+type MediaTag struct {
+	MediaID        int32
+	TagID          int32
+	Rank           int32
+	IsMediaSpoiler bool
+}
+
+type Medium struct {
+	ID               int32
+	Titles           string
+	Type             NullMediaType
+	Format           pgtype.Text
+	Status           string
+	Season           pgtype.Text
+	SeasonYear       pgtype.Int4
+	Episodes         pgtype.Int4
+	Chapters         pgtype.Int4
+	Volumes          pgtype.Int4
+	CoverImage       pgtype.Text
+	Genres           []string
+	AverageScore     pgtype.Int4
+	Studios          []string
+	IsAdult          pgtype.Bool
+	LastUpdated      pgtype.Timestamptz
+	AnilistUpdatedAt pgtype.Int8
+	ContentHash      pgtype.Text
+	LastChecked      pgtype.Timestamptz
+}
+
+type Staff struct {
+	ID         int32
+	NameFull   pgtype.Text
+	NameNative pgtype.Text
+	Image      pgtype.Text
+}
+
+type Studio struct {
+	ID                int32
+	Name              string
+	IsAnimationStudio bool
+}
+
+type SyncLock struct {
+	Name       string
+	Holder     string
+	Command    string
+	BackendPid int32
+	AcquiredAt pgtype.Timestamptz
+}
+
+type SyncRun struct {
+	ID                int64
+	Mode              string
+	Status            string
+	LastCompletedPage int32
+	FailedPages       []int32
+	FailedIds         []int32
+	StartedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+	FinishedAt        pgtype.Timestamptz
+	HighWaterMark     pgtype.Int8
+}
+
+type Tag struct {
+	ID       int32
+	Name     string
+	Category pgtype.Text
+	IsAdult  bool
 }
 
 type User struct {
@@ -14,7 +14,10 @@ INSERT INTO media (id,
                    average_score,
                    studios,
                    is_adult,
-                   last_updated)
+                   anilist_updated_at,
+                   content_hash,
+                   last_updated,
+                   last_checked)
 VALUES ($1,
         ROW($2, $3, $4)::titles,
         $5,
@@ -30,6 +33,9 @@ VALUES ($1,
         $15,
         $16,
         $17,
+        $18,
+        $19,
+        NOW(),
         NOW()
         )
 ON CONFLICT (id) DO UPDATE
@@ -47,7 +53,10 @@ genres        = $14,
 average_score = $15,
 studios       = $16,
 is_adult      = $17,
-last_updated = NOW();
+anilist_updated_at = $18,
+content_hash = $19,
+last_updated = NOW(),
+last_checked = NOW();
 
 
 -- name: PutMediaDetails :exec
@@ -164,4 +173,580 @@ WHERE media.id NOT IN (
                 )
             )
         )
-);
\ No newline at end of file
+);
+-- name: CreateSyncRun :one
+INSERT INTO sync_runs (mode)
+VALUES ($1)
+RETURNING *;
+
+-- name: GetResumableSyncRun :one
+SELECT *
+FROM sync_runs
+WHERE mode = $1
+  AND status <> 'completed'
+ORDER BY started_at DESC
+LIMIT 1;
+
+-- name: ReopenSyncRun :exec
+UPDATE sync_runs
+SET status      = 'running',
+    finished_at = NULL,
+    updated_at  = NOW()
+WHERE id = $1;
+
+-- name: CheckpointSyncRun :exec
+UPDATE sync_runs
+SET last_completed_page = $2,
+    failed_pages        = $3,
+    failed_ids          = $4,
+    updated_at          = NOW()
+WHERE id = $1;
+
+-- name: FinishSyncRun :exec
+UPDATE sync_runs
+SET status      = $2,
+    finished_at = NOW(),
+    updated_at  = NOW()
+WHERE id = $1;
+
+-- name: SetSyncRunHighWaterMark :exec
+UPDATE sync_runs
+SET high_water_mark = $2,
+    updated_at      = NOW()
+WHERE id = $1;
+
+-- name: GetHighWaterMark :one
+SELECT high_water_mark
+FROM sync_runs
+WHERE mode = $1
+  AND status = 'completed'
+  AND high_water_mark IS NOT NULL
+ORDER BY finished_at DESC
+LIMIT 1;
+
+-- name: RecordFailedMedia :exec
+INSERT INTO failed_media (media_id, error, payload)
+VALUES ($1, $2, $3)
+ON CONFLICT (media_id) DO UPDATE
+SET error          = $2,
+    payload        = $3,
+    attempts       = failed_media.attempts + 1,
+    last_failed_at = NOW();
+
+-- name: ListFailedMediaIds :many
+SELECT media_id
+FROM failed_media
+ORDER BY media_id;
+
+-- name: ClearFailedMedia :exec
+DELETE
+FROM failed_media
+WHERE media_id = $1;
+
+-- name: FilterKnownMediaIds :many
+SELECT id
+FROM media
+WHERE id = ANY ($1::INTEGER[]);
+
+-- name: GetMediaUpdatedAt :many
+SELECT id, anilist_updated_at
+FROM media
+WHERE id = ANY ($1::INTEGER[]);
+
+-- name: MarkMediaChecked :execrows
+UPDATE media
+SET last_checked = NOW()
+WHERE id = $1
+  AND content_hash = $2;
+
+-- name: ClearMediaContentHashes :exec
+-- forces the next write of these media to go through the full upsert, used by the writer benchmark
+UPDATE media
+SET content_hash = NULL
+WHERE id = ANY ($1::INTEGER[]);
+
+-- name: RecordMediaHistory :exec
+INSERT INTO media_history (media_id, field, old_value, new_value, sync_run_id)
+SELECT media.id, change.field, change.old_value, change.new_value, sqlc.narg(sync_run_id)::BIGINT
+FROM media
+         LEFT JOIN media_details
+                   ON media.id = media_details.id
+         CROSS JOIN LATERAL (VALUES ('status', media.status, sqlc.arg(status)::TEXT),
+                                    ('episodes', media.episodes::TEXT, sqlc.narg(episodes)::TEXT),
+                                    ('chapters', media.chapters::TEXT, sqlc.narg(chapters)::TEXT),
+                                    ('volumes', media.volumes::TEXT, sqlc.narg(volumes)::TEXT),
+                                    ('average_score', media.average_score::TEXT, sqlc.narg(average_score)::TEXT),
+                                    ('popularity', media_details.popularity::TEXT, sqlc.narg(popularity)::TEXT),
+                                    ('favourites', media_details.favourites::TEXT, sqlc.narg(favourites)::TEXT))
+    AS change (field, old_value, new_value)
+WHERE media.id = sqlc.arg(id)
+  AND change.old_value IS DISTINCT FROM change.new_value;
+
+-- name: GetMediaHistory :many
+SELECT *
+FROM media_history
+WHERE media_id = $1
+ORDER BY observed_at DESC, id DESC;
+
+-- name: GetMediaFieldHistory :many
+SELECT *
+FROM media_history
+WHERE media_id = $1
+  AND field = $2
+ORDER BY observed_at, id;
+
+-- name: SnapshotMedia :execrows
+INSERT INTO media_snapshots (media_id, snapshot_date, average_score, popularity, trending, favourites)
+SELECT media.id, CURRENT_DATE, media.average_score, media_details.popularity, media_details.trending, media_details.favourites
+FROM media
+         JOIN media_details
+              ON media.id = media_details.id
+WHERE media.id = ANY ($1::INTEGER[])
+ON CONFLICT (media_id, snapshot_date) DO UPDATE
+SET average_score = EXCLUDED.average_score,
+    popularity    = EXCLUDED.popularity,
+    trending      = EXCLUDED.trending,
+    favourites    = EXCLUDED.favourites;
+
+-- name: PruneMediaSnapshots :execrows
+DELETE
+FROM media_snapshots
+WHERE (media_id, snapshot_date) IN (
+    SELECT ranked.media_id, ranked.snapshot_date
+    FROM (
+        SELECT media_id,
+               snapshot_date,
+               ROW_NUMBER() OVER (
+                   PARTITION BY media_id, DATE_TRUNC(
+                       CASE WHEN snapshot_date < CURRENT_DATE - sqlc.arg(weekly_days)::INTEGER THEN 'month' ELSE 'week' END,
+                       snapshot_date)
+                   ORDER BY snapshot_date DESC
+               ) AS position
+        FROM media_snapshots
+        WHERE snapshot_date < CURRENT_DATE - sqlc.arg(daily_days)::INTEGER
+    ) ranked
+    WHERE ranked.position > 1
+);
+
+-- name: GetMediaSnapshots :many
+SELECT *
+FROM media_snapshots
+WHERE media_id = $1
+  AND snapshot_date >= $2
+ORDER BY snapshot_date;
+
+-- name: GetRisingMedia :many
+SELECT latest.media_id,
+       (latest.popularity - earlier.popularity)::INTEGER AS popularity_gain
+FROM media_snapshots latest
+         JOIN media_snapshots earlier
+              ON earlier.media_id = latest.media_id
+                  AND earlier.snapshot_date = latest.snapshot_date - 7
+WHERE latest.snapshot_date = (SELECT MAX(snapshot_date) FROM media_snapshots)
+ORDER BY popularity_gain DESC
+LIMIT $1;
+
+-- name: UpsertGenres :exec
+INSERT INTO genres (name)
+SELECT UNNEST(sqlc.arg(names)::TEXT[])
+ON CONFLICT (name) DO NOTHING;
+
+-- name: SetMediaGenres :exec
+WITH removed AS (
+    DELETE
+    FROM media_genres
+    WHERE media_genres.media_id = sqlc.arg(media_id)::INTEGER
+      AND genre_id NOT IN (SELECT id FROM genres WHERE name = ANY (sqlc.arg(names)::TEXT[]))
+)
+INSERT INTO media_genres (media_id, genre_id)
+SELECT sqlc.arg(media_id)::INTEGER, id
+FROM genres
+WHERE name = ANY (sqlc.arg(names)::TEXT[])
+ON CONFLICT DO NOTHING;
+
+-- name: SetMediaStudios :exec
+WITH upserted AS (
+    INSERT INTO studios (id, name, is_animation_studio)
+    SELECT *
+    FROM UNNEST(sqlc.arg(studio_ids)::INTEGER[], sqlc.arg(names)::TEXT[], sqlc.arg(is_animation_studios)::BOOLEAN[])
+    ON CONFLICT (id) DO UPDATE
+    SET name                = EXCLUDED.name,
+        is_animation_studio = EXCLUDED.is_animation_studio
+    WHERE studios.name IS DISTINCT FROM EXCLUDED.name
+       OR studios.is_animation_studio IS DISTINCT FROM EXCLUDED.is_animation_studio
+),
+removed AS (
+    DELETE
+    FROM media_studios
+    WHERE media_studios.media_id = sqlc.arg(media_id)::INTEGER
+      AND studio_id <> ALL (sqlc.arg(studio_ids)::INTEGER[])
+)
+INSERT INTO media_studios (media_id, studio_id)
+SELECT sqlc.arg(media_id)::INTEGER, UNNEST(sqlc.arg(studio_ids)::INTEGER[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListMediaByStudio :many
+SELECT media.*
+FROM media
+         JOIN media_studios
+              ON media.id = media_studios.media_id
+WHERE media_studios.studio_id = $1
+ORDER BY media.id
+LIMIT $2 OFFSET $3;
+
+-- name: ListMediaByGenre :many
+SELECT media.*
+FROM media
+         JOIN media_genres
+              ON media.id = media_genres.media_id
+         JOIN genres
+              ON genres.id = media_genres.genre_id
+WHERE genres.name = $1
+ORDER BY media.id
+LIMIT $2 OFFSET $3;
+
+-- name: CountMediaByGenre :many
+SELECT genres.name, COUNT(media_genres.media_id) AS media_count
+FROM genres
+         LEFT JOIN media_genres
+                   ON genres.id = media_genres.genre_id
+GROUP BY genres.id, genres.name
+ORDER BY media_count DESC;
+
+-- name: SetMediaTags :exec
+WITH upserted AS (
+    INSERT INTO tags (id, name, category, is_adult)
+    SELECT *
+    FROM UNNEST(sqlc.arg(tag_ids)::INTEGER[], sqlc.arg(names)::TEXT[], sqlc.arg(categories)::TEXT[],
+                sqlc.arg(is_adults)::BOOLEAN[])
+    ON CONFLICT (id) DO UPDATE
+    SET name     = EXCLUDED.name,
+        category = EXCLUDED.category,
+        is_adult = EXCLUDED.is_adult
+    WHERE (tags.name, tags.category, tags.is_adult) IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.category, EXCLUDED.is_adult)
+),
+removed AS (
+    DELETE
+    FROM media_tags
+    WHERE media_tags.media_id = sqlc.arg(media_id)::INTEGER
+      AND tag_id <> ALL (sqlc.arg(tag_ids)::INTEGER[])
+)
+INSERT INTO media_tags (media_id, tag_id, rank, is_media_spoiler)
+SELECT sqlc.arg(media_id)::INTEGER, link.tag_id, link.rank, link.is_media_spoiler
+FROM UNNEST(sqlc.arg(tag_ids)::INTEGER[], sqlc.arg(ranks)::INTEGER[], sqlc.arg(is_media_spoilers)::BOOLEAN[])
+         AS link (tag_id, rank, is_media_spoiler)
+ON CONFLICT (media_id, tag_id) DO UPDATE
+SET rank             = EXCLUDED.rank,
+    is_media_spoiler = EXCLUDED.is_media_spoiler
+WHERE (media_tags.rank, media_tags.is_media_spoiler) IS DISTINCT FROM (EXCLUDED.rank, EXCLUDED.is_media_spoiler);
+
+-- name: ListMediaByTag :many
+SELECT media.*, media_tags.rank, media_tags.is_media_spoiler
+FROM media
+         JOIN media_tags
+              ON media.id = media_tags.media_id
+         JOIN tags
+              ON tags.id = media_tags.tag_id
+WHERE tags.name = $1
+  AND media_tags.rank >= $2
+ORDER BY media_tags.rank DESC, media.id
+LIMIT $3 OFFSET $4;
+
+-- name: SetMediaRelations :exec
+WITH removed AS (
+    DELETE
+    FROM media_relations
+    WHERE media_relations.media_id = sqlc.arg(media_id)::INTEGER
+      AND related_id <> ALL (sqlc.arg(related_ids)::INTEGER[])
+)
+INSERT INTO media_relations (media_id, related_id, relation_type)
+SELECT sqlc.arg(media_id)::INTEGER, link.related_id, link.relation_type
+FROM UNNEST(sqlc.arg(related_ids)::INTEGER[], sqlc.arg(relation_types)::TEXT[]) AS link (related_id, relation_type)
+ON CONFLICT (media_id, related_id) DO UPDATE
+SET relation_type = EXCLUDED.relation_type
+WHERE media_relations.relation_type IS DISTINCT FROM EXCLUDED.relation_type;
+
+-- name: GetFranchiseWatchOrder :many
+-- walks story relations in both directions since AniList does not always link both ways,
+-- CHARACTER, OTHER, COMPILATION and CONTAINS links would pull in unrelated franchises,
+-- manga and novels are walked through so adaptations connect but only anime is returned
+WITH RECURSIVE franchise (id) AS (
+    SELECT media.id
+    FROM media
+    WHERE media.id = $1
+    UNION
+    SELECT CASE WHEN media_relations.media_id = franchise.id THEN media_relations.related_id ELSE media_relations.media_id END
+    FROM franchise
+             JOIN media_relations
+                  ON franchise.id IN (media_relations.media_id, media_relations.related_id)
+    WHERE media_relations.relation_type IN
+          ('PREQUEL', 'SEQUEL', 'PARENT', 'SIDE_STORY', 'SPIN_OFF', 'ALTERNATIVE', 'SUMMARY', 'ADAPTATION', 'SOURCE')
+)
+SELECT media.id, media.titles, media.type, media.format, media.season_year, media_details.start_date
+FROM franchise
+         JOIN media
+              ON media.id = franchise.id
+         LEFT JOIN media_details
+                   ON media_details.id = media.id
+WHERE media.type = 'ANIME'
+ORDER BY NULLIF((media_details.start_date).year, 0) NULLS LAST,
+         NULLIF((media_details.start_date).month, 0) NULLS LAST,
+         NULLIF((media_details.start_date).day, 0) NULLS LAST,
+         media.id;
+
+-- name: ListMediaForCreditSync :many
+SELECT media.id
+FROM media
+         LEFT JOIN credit_syncs
+                   ON credit_syncs.media_id = media.id
+                       AND credit_syncs.credit = $1
+ORDER BY credit_syncs.synced_at NULLS FIRST, media.id
+LIMIT $2;
+
+-- name: MarkCreditSynced :exec
+INSERT INTO credit_syncs (media_id, credit, synced_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (media_id, credit) DO UPDATE
+SET synced_at = NOW();
+
+-- name: UpsertCharacters :exec
+INSERT INTO characters (id, name_full, name_native, image)
+SELECT input.id, NULLIF(input.name_full, ''), NULLIF(input.name_native, ''), NULLIF(input.image, '')
+FROM UNNEST(sqlc.arg(ids)::INTEGER[], sqlc.arg(name_fulls)::TEXT[], sqlc.arg(name_natives)::TEXT[],
+            sqlc.arg(images)::TEXT[]) AS input (id, name_full, name_native, image)
+ON CONFLICT (id) DO UPDATE
+SET name_full   = EXCLUDED.name_full,
+    name_native = EXCLUDED.name_native,
+    image       = EXCLUDED.image
+WHERE (characters.name_full, characters.name_native, characters.image) IS DISTINCT FROM
+      (EXCLUDED.name_full, EXCLUDED.name_native, EXCLUDED.image);
+
+-- name: UpsertStaff :exec
+INSERT INTO staff (id, name_full, name_native, image)
+SELECT input.id, NULLIF(input.name_full, ''), NULLIF(input.name_native, ''), NULLIF(input.image, '')
+FROM UNNEST(sqlc.arg(ids)::INTEGER[], sqlc.arg(name_fulls)::TEXT[], sqlc.arg(name_natives)::TEXT[],
+            sqlc.arg(images)::TEXT[]) AS input (id, name_full, name_native, image)
+ON CONFLICT (id) DO UPDATE
+SET name_full   = EXCLUDED.name_full,
+    name_native = EXCLUDED.name_native,
+    image       = COALESCE(EXCLUDED.image, staff.image)
+WHERE (staff.name_full, staff.name_native) IS DISTINCT FROM (EXCLUDED.name_full, EXCLUDED.name_native)
+   OR (EXCLUDED.image IS NOT NULL AND staff.image IS DISTINCT FROM EXCLUDED.image);
+
+-- name: SetMediaCharacters :exec
+WITH removed AS (
+    DELETE
+    FROM media_characters
+    WHERE media_characters.media_id = sqlc.arg(media_id)::INTEGER
+      AND character_id <> ALL (sqlc.arg(character_ids)::INTEGER[])
+)
+INSERT INTO media_characters (media_id, character_id, role, position)
+SELECT sqlc.arg(media_id)::INTEGER, link.character_id, link.role, link.position
+FROM UNNEST(sqlc.arg(character_ids)::INTEGER[], sqlc.arg(roles)::TEXT[]) WITH ORDINALITY
+         AS link (character_id, role, position)
+ON CONFLICT (media_id, character_id) DO UPDATE
+SET role     = EXCLUDED.role,
+    position = EXCLUDED.position
+WHERE (media_characters.role, media_characters.position) IS DISTINCT FROM (EXCLUDED.role, EXCLUDED.position);
+
+-- name: SetCharacterVoiceActors :exec
+WITH removed AS (
+    DELETE
+    FROM character_voice_actors
+    WHERE character_voice_actors.media_id = sqlc.arg(media_id)::INTEGER
+      AND character_voice_actors.language = sqlc.arg(language)::TEXT
+      AND (character_id, staff_id) NOT IN
+          (SELECT * FROM UNNEST(sqlc.arg(character_ids)::INTEGER[], sqlc.arg(staff_ids)::INTEGER[]))
+)
+INSERT INTO character_voice_actors (media_id, character_id, staff_id, language)
+SELECT sqlc.arg(media_id)::INTEGER, link.character_id, link.staff_id, sqlc.arg(language)::TEXT
+FROM UNNEST(sqlc.arg(character_ids)::INTEGER[], sqlc.arg(staff_ids)::INTEGER[]) AS link (character_id, staff_id)
+ON CONFLICT DO NOTHING;
+
+-- name: ListMediaCharacters :many
+SELECT characters.id, characters.name_full, characters.name_native, characters.image, media_characters.role,
+       staff.id AS voice_actor_id, staff.name_full AS voice_actor_name_full, staff.name_native AS voice_actor_name_native
+FROM media_characters
+         JOIN characters
+              ON characters.id = media_characters.character_id
+         LEFT JOIN character_voice_actors
+                   ON character_voice_actors.media_id = media_characters.media_id
+                       AND character_voice_actors.character_id = media_characters.character_id
+         LEFT JOIN staff
+                   ON staff.id = character_voice_actors.staff_id
+WHERE media_characters.media_id = $1
+ORDER BY media_characters.position, staff.id;
+
+-- name: SetMediaStaff :exec
+WITH removed AS (
+    DELETE
+    FROM media_staff
+    WHERE media_staff.media_id = sqlc.arg(media_id)::INTEGER
+      AND (staff_id, role) NOT IN (SELECT * FROM UNNEST(sqlc.arg(staff_ids)::INTEGER[], sqlc.arg(roles)::TEXT[]))
+)
+INSERT INTO media_staff (media_id, staff_id, role, position)
+SELECT sqlc.arg(media_id)::INTEGER, link.staff_id, link.role, link.position
+FROM UNNEST(sqlc.arg(staff_ids)::INTEGER[], sqlc.arg(roles)::TEXT[]) WITH ORDINALITY
+         AS link (staff_id, role, position)
+ON CONFLICT (media_id, staff_id, role) DO UPDATE
+SET position = EXCLUDED.position
+WHERE media_staff.position IS DISTINCT FROM EXCLUDED.position;
+
+-- name: ListMediaStaff :many
+SELECT staff.id, staff.name_full, staff.name_native, staff.image, media_staff.role
+FROM media_staff
+         JOIN staff
+              ON staff.id = media_staff.staff_id
+WHERE media_staff.media_id = $1
+ORDER BY media_staff.position;
+
+-- name: ListStaffCredits :many
+SELECT media.id, media.titles, media.type, media.format, media.season_year, media_staff.role
+FROM media_staff
+         JOIN media
+              ON media.id = media_staff.media_id
+WHERE media_staff.staff_id = $1
+ORDER BY media.season_year DESC NULLS LAST, media.id;
+
+-- name: SetAiringEpisodes :exec
+-- upcoming episodes AniList no longer lists are dropped, aired ones are kept as history
+WITH removed AS (
+    DELETE
+    FROM airing_episodes
+    WHERE airing_episodes.media_id = sqlc.arg(media_id)::INTEGER
+      AND airing_episodes.airing_at > EXTRACT(EPOCH FROM NOW())::BIGINT
+      AND episode <> ALL (sqlc.arg(episodes)::INTEGER[])
+)
+INSERT INTO airing_episodes (media_id, episode, airing_at)
+SELECT sqlc.arg(media_id)::INTEGER, schedule.episode, schedule.airing_at
+FROM UNNEST(sqlc.arg(episodes)::INTEGER[], sqlc.arg(airing_ats)::BIGINT[]) AS schedule (episode, airing_at)
+ON CONFLICT (media_id, episode) DO UPDATE
+SET airing_at          = EXCLUDED.airing_at,
+    previous_airing_at = airing_episodes.airing_at,
+    rescheduled_at     = NOW(),
+    reschedule_count   = airing_episodes.reschedule_count + 1,
+    updated_at         = NOW()
+WHERE airing_episodes.airing_at IS DISTINCT FROM EXCLUDED.airing_at;
+
+-- name: PutAiredEpisodes :execrows
+-- final air times of recently aired episodes, media we do not store are skipped
+INSERT INTO airing_episodes (media_id, episode, airing_at)
+SELECT aired.media_id, aired.episode, aired.airing_at
+FROM UNNEST(sqlc.arg(media_ids)::INTEGER[], sqlc.arg(episodes)::INTEGER[],
+            sqlc.arg(airing_ats)::BIGINT[]) AS aired (media_id, episode, airing_at)
+WHERE EXISTS (SELECT 1 FROM media WHERE media.id = aired.media_id)
+ON CONFLICT (media_id, episode) DO UPDATE
+SET airing_at          = EXCLUDED.airing_at,
+    previous_airing_at = airing_episodes.airing_at,
+    rescheduled_at     = NOW(),
+    reschedule_count   = airing_episodes.reschedule_count + 1,
+    updated_at         = NOW()
+WHERE airing_episodes.airing_at IS DISTINCT FROM EXCLUDED.airing_at;
+
+-- name: ListAiringEpisodes :many
+SELECT media_id, episode, airing_at, previous_airing_at, rescheduled_at
+FROM airing_episodes
+WHERE airing_at >= sqlc.arg(start_at)::BIGINT
+  AND airing_at < sqlc.arg(end_at)::BIGINT
+ORDER BY airing_at, media_id;
+
+-- name: ListRescheduledEpisodes :many
+SELECT media_id, episode, airing_at, previous_airing_at, rescheduled_at
+FROM airing_episodes
+WHERE rescheduled_at >= $1
+ORDER BY rescheduled_at DESC;
+
+-- name: ListCalendarEpisodes :many
+-- every filter but since is optional, a season filter needs both season and season_year
+SELECT airing_episodes.media_id,
+       airing_episodes.episode,
+       airing_episodes.airing_at,
+       airing_episodes.previous_airing_at,
+       airing_episodes.reschedule_count,
+       airing_episodes.updated_at,
+       COALESCE(NULLIF((media.titles).english, ''), NULLIF((media.titles).romaji, ''), '')::TEXT AS title,
+       media_details.duration
+FROM airing_episodes
+         JOIN media
+              ON media.id = airing_episodes.media_id
+         LEFT JOIN media_details
+                   ON media_details.id = media.id
+WHERE airing_episodes.airing_at >= sqlc.arg(since)::BIGINT
+  AND (sqlc.narg(media_id)::INTEGER IS NULL OR airing_episodes.media_id = sqlc.narg(media_id)::INTEGER)
+  AND (sqlc.narg(season)::TEXT IS NULL OR
+       (media.season = sqlc.narg(season)::TEXT AND media.season_year = sqlc.narg(season_year)::INTEGER))
+  AND (sqlc.narg(user_id)::UUID IS NULL OR EXISTS (SELECT 1
+                                                   FROM watchlist
+                                                   WHERE watchlist.user_id = sqlc.narg(user_id)::UUID
+                                                     AND watchlist.media_id = media.id
+                                                     AND watchlist.status IN ('watching', 'planning')))
+ORDER BY airing_episodes.airing_at, airing_episodes.media_id;
+
+-- name: ListCalendarMedia :many
+SELECT DISTINCT media_id
+FROM airing_episodes
+WHERE airing_at >= $1
+ORDER BY media_id;
+
+-- name: ListCalendarSeasons :many
+SELECT DISTINCT media.season, media.season_year
+FROM airing_episodes
+         JOIN media
+              ON media.id = airing_episodes.media_id
+WHERE airing_episodes.airing_at >= $1
+  AND media.season IS NOT NULL
+  AND media.season_year IS NOT NULL
+ORDER BY media.season_year, media.season;
+
+-- name: ListWatchlistUsers :many
+SELECT DISTINCT user_id
+FROM watchlist
+WHERE user_id IS NOT NULL
+ORDER BY user_id;
+
+-- name: ListRecentSyncRuns :many
+SELECT *
+FROM sync_runs
+ORDER BY started_at DESC
+LIMIT $1;
+
+-- name: CountFailedMedia :one
+SELECT COUNT(*)
+FROM failed_media;
+
+-- name: TryAcquireSyncLock :one
+-- 7411 namespaces our advisory locks away from anything else on the database
+SELECT pg_try_advisory_lock(7411, hashtext(sqlc.arg(name)::TEXT));
+
+-- name: AcquireSyncLock :exec
+SELECT pg_advisory_lock(7411, hashtext(sqlc.arg(name)::TEXT));
+
+-- name: ReleaseSyncLock :one
+SELECT pg_advisory_unlock(7411, hashtext(sqlc.arg(name)::TEXT));
+
+-- name: RecordSyncLockHolder :exec
+INSERT INTO sync_locks (name, holder, command, backend_pid)
+VALUES ($1, $2, $3, pg_backend_pid())
+ON CONFLICT (name) DO UPDATE
+    SET holder      = excluded.holder,
+        command     = excluded.command,
+        backend_pid = excluded.backend_pid,
+        acquired_at = NOW();
+
+-- name: ClearSyncLockHolder :exec
+DELETE
+FROM sync_locks
+WHERE name = $1
+  AND backend_pid = pg_backend_pid();
+
+-- name: ListSyncLockHolders :many
+-- held is false for rows left behind by a holder whose session is gone
+SELECT sync_locks.*,
+       EXISTS (SELECT 1
+               FROM pg_locks
+               WHERE pg_locks.locktype = 'advisory'
+                 AND pg_locks.classid = 7411
+                 AND pg_locks.pid = sync_locks.backend_pid
+                 AND pg_locks.granted) AS held
+FROM sync_locks
+ORDER BY name;
@@ -12,6 +12,1339 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acquireSyncLock = `-- name: AcquireSyncLock :exec
+SELECT pg_advisory_lock(7411, hashtext($1::TEXT))
+`
+
+func (q *Queries) AcquireSyncLock(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, acquireSyncLock, name)
+	return err
+}
+
+const checkpointSyncRun = `-- name: CheckpointSyncRun :exec
+UPDATE sync_runs
+SET last_completed_page = $2,
+    failed_pages        = $3,
+    failed_ids          = $4,
+    updated_at          = NOW()
+WHERE id = $1
+`
+
+type CheckpointSyncRunParams struct {
+	ID                int64
+	LastCompletedPage int32
+	FailedPages       []int32
+	FailedIds         []int32
+}
+
+func (q *Queries) CheckpointSyncRun(ctx context.Context, arg CheckpointSyncRunParams) error {
+	_, err := q.db.Exec(ctx, checkpointSyncRun,
+		arg.ID,
+		arg.LastCompletedPage,
+		arg.FailedPages,
+		arg.FailedIds,
+	)
+	return err
+}
+
+const clearFailedMedia = `-- name: ClearFailedMedia :exec
+DELETE
+FROM failed_media
+WHERE media_id = $1
+`
+
+func (q *Queries) ClearFailedMedia(ctx context.Context, mediaID int32) error {
+	_, err := q.db.Exec(ctx, clearFailedMedia, mediaID)
+	return err
+}
+
+const clearMediaContentHashes = `-- name: ClearMediaContentHashes :exec
+UPDATE media
+SET content_hash = NULL
+WHERE id = ANY ($1::INTEGER[])
+`
+
+// forces the next write of these media to go through the full upsert, used by the writer benchmark
+func (q *Queries) ClearMediaContentHashes(ctx context.Context, dollar_1 []int32) error {
+	_, err := q.db.Exec(ctx, clearMediaContentHashes, dollar_1)
+	return err
+}
+
+const clearSyncLockHolder = `-- name: ClearSyncLockHolder :exec
+DELETE
+FROM sync_locks
+WHERE name = $1
+  AND backend_pid = pg_backend_pid()
+`
+
+func (q *Queries) ClearSyncLockHolder(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, clearSyncLockHolder, name)
+	return err
+}
+
+const countFailedMedia = `-- name: CountFailedMedia :one
+SELECT COUNT(*)
+FROM failed_media
+`
+
+func (q *Queries) CountFailedMedia(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countFailedMedia)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countMediaByGenre = `-- name: CountMediaByGenre :many
+SELECT genres.name, COUNT(media_genres.media_id) AS media_count
+FROM genres
+         LEFT JOIN media_genres
+                   ON genres.id = media_genres.genre_id
+GROUP BY genres.id, genres.name
+ORDER BY media_count DESC
+`
+
+type CountMediaByGenreRow struct {
+	Name       string
+	MediaCount int64
+}
+
+func (q *Queries) CountMediaByGenre(ctx context.Context) ([]CountMediaByGenreRow, error) {
+	rows, err := q.db.Query(ctx, countMediaByGenre)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountMediaByGenreRow
+	for rows.Next() {
+		var i CountMediaByGenreRow
+		if err := rows.Scan(&i.Name, &i.MediaCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createSyncRun = `-- name: CreateSyncRun :one
+INSERT INTO sync_runs (mode)
+VALUES ($1)
+RETURNING id, mode, status, last_completed_page, failed_pages, failed_ids, started_at, updated_at, finished_at, high_water_mark
+`
+
+func (q *Queries) CreateSyncRun(ctx context.Context, mode string) (SyncRun, error) {
+	row := q.db.QueryRow(ctx, createSyncRun, mode)
+	var i SyncRun
+	err := row.Scan(
+		&i.ID,
+		&i.Mode,
+		&i.Status,
+		&i.LastCompletedPage,
+		&i.FailedPages,
+		&i.FailedIds,
+		&i.StartedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+		&i.HighWaterMark,
+	)
+	return i, err
+}
+
+const filterKnownMediaIds = `-- name: FilterKnownMediaIds :many
+SELECT id
+FROM media
+WHERE id = ANY ($1::INTEGER[])
+`
+
+func (q *Queries) FilterKnownMediaIds(ctx context.Context, dollar_1 []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, filterKnownMediaIds, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const finishSyncRun = `-- name: FinishSyncRun :exec
+UPDATE sync_runs
+SET status      = $2,
+    finished_at = NOW(),
+    updated_at  = NOW()
+WHERE id = $1
+`
+
+type FinishSyncRunParams struct {
+	ID     int64
+	Status string
+}
+
+func (q *Queries) FinishSyncRun(ctx context.Context, arg FinishSyncRunParams) error {
+	_, err := q.db.Exec(ctx, finishSyncRun, arg.ID, arg.Status)
+	return err
+}
+
+const getFranchiseWatchOrder = `-- name: GetFranchiseWatchOrder :many
+WITH RECURSIVE franchise (id) AS (
+    SELECT media.id
+    FROM media
+    WHERE media.id = $1
+    UNION
+    SELECT CASE WHEN media_relations.media_id = franchise.id THEN media_relations.related_id ELSE media_relations.media_id END
+    FROM franchise
+             JOIN media_relations
+                  ON franchise.id IN (media_relations.media_id, media_relations.related_id)
+    WHERE media_relations.relation_type IN
+          ('PREQUEL', 'SEQUEL', 'PARENT', 'SIDE_STORY', 'SPIN_OFF', 'ALTERNATIVE', 'SUMMARY', 'ADAPTATION', 'SOURCE')
+)
+SELECT media.id, media.titles, media.type, media.format, media.season_year, media_details.start_date
+FROM franchise
+         JOIN media
+              ON media.id = franchise.id
+         LEFT JOIN media_details
+                   ON media_details.id = media.id
+WHERE media.type = 'ANIME'
+ORDER BY NULLIF((media_details.start_date).year, 0) NULLS LAST,
+         NULLIF((media_details.start_date).month, 0) NULLS LAST,
+         NULLIF((media_details.start_date).day, 0) NULLS LAST,
+         media.id
+`
+
+type GetFranchiseWatchOrderRow struct {
+	ID         int32
+	Titles     string
+	Type       NullMediaType
+	Format     pgtype.Text
+	SeasonYear pgtype.Int4
+	StartDate  sql.NullString
+}
+
+// walks story relations in both directions since AniList does not always link both ways,
+// CHARACTER, OTHER, COMPILATION and CONTAINS links would pull in unrelated franchises,
+// manga and novels are walked through so adaptations connect but only anime is returned
+func (q *Queries) GetFranchiseWatchOrder(ctx context.Context, id int32) ([]GetFranchiseWatchOrderRow, error) {
+	rows, err := q.db.Query(ctx, getFranchiseWatchOrder, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFranchiseWatchOrderRow
+	for rows.Next() {
+		var i GetFranchiseWatchOrderRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Titles,
+			&i.Type,
+			&i.Format,
+			&i.SeasonYear,
+			&i.StartDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHighWaterMark = `-- name: GetHighWaterMark :one
+SELECT high_water_mark
+FROM sync_runs
+WHERE mode = $1
+  AND status = 'completed'
+  AND high_water_mark IS NOT NULL
+ORDER BY finished_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetHighWaterMark(ctx context.Context, mode string) (pgtype.Int8, error) {
+	row := q.db.QueryRow(ctx, getHighWaterMark, mode)
+	var high_water_mark pgtype.Int8
+	err := row.Scan(&high_water_mark)
+	return high_water_mark, err
+}
+
+const getMediaFieldHistory = `-- name: GetMediaFieldHistory :many
+SELECT id, media_id, field, old_value, new_value, observed_at, sync_run_id
+FROM media_history
+WHERE media_id = $1
+  AND field = $2
+ORDER BY observed_at, id
+`
+
+type GetMediaFieldHistoryParams struct {
+	MediaID int32
+	Field   string
+}
+
+func (q *Queries) GetMediaFieldHistory(ctx context.Context, arg GetMediaFieldHistoryParams) ([]MediaHistory, error) {
+	rows, err := q.db.Query(ctx, getMediaFieldHistory, arg.MediaID, arg.Field)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaHistory
+	for rows.Next() {
+		var i MediaHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Field,
+			&i.OldValue,
+			&i.NewValue,
+			&i.ObservedAt,
+			&i.SyncRunID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaHistory = `-- name: GetMediaHistory :many
+SELECT id, media_id, field, old_value, new_value, observed_at, sync_run_id
+FROM media_history
+WHERE media_id = $1
+ORDER BY observed_at DESC, id DESC
+`
+
+func (q *Queries) GetMediaHistory(ctx context.Context, mediaID int32) ([]MediaHistory, error) {
+	rows, err := q.db.Query(ctx, getMediaHistory, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaHistory
+	for rows.Next() {
+		var i MediaHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Field,
+			&i.OldValue,
+			&i.NewValue,
+			&i.ObservedAt,
+			&i.SyncRunID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaSnapshots = `-- name: GetMediaSnapshots :many
+SELECT media_id, snapshot_date, average_score, popularity, trending, favourites
+FROM media_snapshots
+WHERE media_id = $1
+  AND snapshot_date >= $2
+ORDER BY snapshot_date
+`
+
+type GetMediaSnapshotsParams struct {
+	MediaID      int32
+	SnapshotDate pgtype.Date
+}
+
+func (q *Queries) GetMediaSnapshots(ctx context.Context, arg GetMediaSnapshotsParams) ([]MediaSnapshot, error) {
+	rows, err := q.db.Query(ctx, getMediaSnapshots, arg.MediaID, arg.SnapshotDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaSnapshot
+	for rows.Next() {
+		var i MediaSnapshot
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.SnapshotDate,
+			&i.AverageScore,
+			&i.Popularity,
+			&i.Trending,
+			&i.Favourites,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaUpdatedAt = `-- name: GetMediaUpdatedAt :many
+SELECT id, anilist_updated_at
+FROM media
+WHERE id = ANY ($1::INTEGER[])
+`
+
+type GetMediaUpdatedAtRow struct {
+	ID               int32
+	AnilistUpdatedAt pgtype.Int8
+}
+
+func (q *Queries) GetMediaUpdatedAt(ctx context.Context, dollar_1 []int32) ([]GetMediaUpdatedAtRow, error) {
+	rows, err := q.db.Query(ctx, getMediaUpdatedAt, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMediaUpdatedAtRow
+	for rows.Next() {
+		var i GetMediaUpdatedAtRow
+		if err := rows.Scan(&i.ID, &i.AnilistUpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getResumableSyncRun = `-- name: GetResumableSyncRun :one
+SELECT id, mode, status, last_completed_page, failed_pages, failed_ids, started_at, updated_at, finished_at, high_water_mark
+FROM sync_runs
+WHERE mode = $1
+  AND status <> 'completed'
+ORDER BY started_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetResumableSyncRun(ctx context.Context, mode string) (SyncRun, error) {
+	row := q.db.QueryRow(ctx, getResumableSyncRun, mode)
+	var i SyncRun
+	err := row.Scan(
+		&i.ID,
+		&i.Mode,
+		&i.Status,
+		&i.LastCompletedPage,
+		&i.FailedPages,
+		&i.FailedIds,
+		&i.StartedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+		&i.HighWaterMark,
+	)
+	return i, err
+}
+
+const getRisingMedia = `-- name: GetRisingMedia :many
+SELECT latest.media_id,
+       (latest.popularity - earlier.popularity)::INTEGER AS popularity_gain
+FROM media_snapshots latest
+         JOIN media_snapshots earlier
+              ON earlier.media_id = latest.media_id
+                  AND earlier.snapshot_date = latest.snapshot_date - 7
+WHERE latest.snapshot_date = (SELECT MAX(snapshot_date) FROM media_snapshots)
+ORDER BY popularity_gain DESC
+LIMIT $1
+`
+
+type GetRisingMediaRow struct {
+	MediaID        int32
+	PopularityGain int32
+}
+
+func (q *Queries) GetRisingMedia(ctx context.Context, limit int32) ([]GetRisingMediaRow, error) {
+	rows, err := q.db.Query(ctx, getRisingMedia, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRisingMediaRow
+	for rows.Next() {
+		var i GetRisingMediaRow
+		if err := rows.Scan(&i.MediaID, &i.PopularityGain); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAiringEpisodes = `-- name: ListAiringEpisodes :many
+SELECT media_id, episode, airing_at, previous_airing_at, rescheduled_at
+FROM airing_episodes
+WHERE airing_at >= $1::BIGINT
+  AND airing_at < $2::BIGINT
+ORDER BY airing_at, media_id
+`
+
+type ListAiringEpisodesParams struct {
+	StartAt int64
+	EndAt   int64
+}
+
+type ListAiringEpisodesRow struct {
+	MediaID          int32
+	Episode          int32
+	AiringAt         int64
+	PreviousAiringAt pgtype.Int8
+	RescheduledAt    pgtype.Timestamptz
+}
+
+func (q *Queries) ListAiringEpisodes(ctx context.Context, arg ListAiringEpisodesParams) ([]ListAiringEpisodesRow, error) {
+	rows, err := q.db.Query(ctx, listAiringEpisodes, arg.StartAt, arg.EndAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAiringEpisodesRow
+	for rows.Next() {
+		var i ListAiringEpisodesRow
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Episode,
+			&i.AiringAt,
+			&i.PreviousAiringAt,
+			&i.RescheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCalendarEpisodes = `-- name: ListCalendarEpisodes :many
+SELECT airing_episodes.media_id,
+       airing_episodes.episode,
+       airing_episodes.airing_at,
+       airing_episodes.previous_airing_at,
+       airing_episodes.reschedule_count,
+       airing_episodes.updated_at,
+       COALESCE(NULLIF((media.titles).english, ''), NULLIF((media.titles).romaji, ''), '')::TEXT AS title,
+       media_details.duration
+FROM airing_episodes
+         JOIN media
+              ON media.id = airing_episodes.media_id
+         LEFT JOIN media_details
+                   ON media_details.id = media.id
+WHERE airing_episodes.airing_at >= $1::BIGINT
+  AND ($2::INTEGER IS NULL OR airing_episodes.media_id = $2::INTEGER)
+  AND ($3::TEXT IS NULL OR
+       (media.season = $3::TEXT AND media.season_year = $4::INTEGER))
+  AND ($5::UUID IS NULL OR EXISTS (SELECT 1
+                                                   FROM watchlist
+                                                   WHERE watchlist.user_id = $5::UUID
+                                                     AND watchlist.media_id = media.id
+                                                     AND watchlist.status IN ('watching', 'planning')))
+ORDER BY airing_episodes.airing_at, airing_episodes.media_id
+`
+
+type ListCalendarEpisodesParams struct {
+	Since      int64
+	MediaID    pgtype.Int4
+	Season     pgtype.Text
+	SeasonYear pgtype.Int4
+	UserID     pgtype.UUID
+}
+
+type ListCalendarEpisodesRow struct {
+	MediaID          int32
+	Episode          int32
+	AiringAt         int64
+	PreviousAiringAt pgtype.Int8
+	RescheduleCount  int32
+	UpdatedAt        pgtype.Timestamptz
+	Title            string
+	Duration         pgtype.Int4
+}
+
+// every filter but since is optional, a season filter needs both season and season_year
+func (q *Queries) ListCalendarEpisodes(ctx context.Context, arg ListCalendarEpisodesParams) ([]ListCalendarEpisodesRow, error) {
+	rows, err := q.db.Query(ctx, listCalendarEpisodes,
+		arg.Since,
+		arg.MediaID,
+		arg.Season,
+		arg.SeasonYear,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCalendarEpisodesRow
+	for rows.Next() {
+		var i ListCalendarEpisodesRow
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Episode,
+			&i.AiringAt,
+			&i.PreviousAiringAt,
+			&i.RescheduleCount,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Duration,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCalendarMedia = `-- name: ListCalendarMedia :many
+SELECT DISTINCT media_id
+FROM airing_episodes
+WHERE airing_at >= $1
+ORDER BY media_id
+`
+
+func (q *Queries) ListCalendarMedia(ctx context.Context, airingAt int64) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listCalendarMedia, airingAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var media_id int32
+		if err := rows.Scan(&media_id); err != nil {
+			return nil, err
+		}
+		items = append(items, media_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCalendarSeasons = `-- name: ListCalendarSeasons :many
+SELECT DISTINCT media.season, media.season_year
+FROM airing_episodes
+         JOIN media
+              ON media.id = airing_episodes.media_id
+WHERE airing_episodes.airing_at >= $1
+  AND media.season IS NOT NULL
+  AND media.season_year IS NOT NULL
+ORDER BY media.season_year, media.season
+`
+
+type ListCalendarSeasonsRow struct {
+	Season     pgtype.Text
+	SeasonYear pgtype.Int4
+}
+
+func (q *Queries) ListCalendarSeasons(ctx context.Context, airingAt int64) ([]ListCalendarSeasonsRow, error) {
+	rows, err := q.db.Query(ctx, listCalendarSeasons, airingAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCalendarSeasonsRow
+	for rows.Next() {
+		var i ListCalendarSeasonsRow
+		if err := rows.Scan(&i.Season, &i.SeasonYear); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFailedMediaIds = `-- name: ListFailedMediaIds :many
+SELECT media_id
+FROM failed_media
+ORDER BY media_id
+`
+
+func (q *Queries) ListFailedMediaIds(ctx context.Context) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listFailedMediaIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var media_id int32
+		if err := rows.Scan(&media_id); err != nil {
+			return nil, err
+		}
+		items = append(items, media_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByGenre = `-- name: ListMediaByGenre :many
+SELECT media.id, media.titles, media.type, media.format, media.status, media.season, media.season_year, media.episodes, media.chapters, media.volumes, media.cover_image, media.genres, media.average_score, media.studios, media.is_adult, media.last_updated, media.anilist_updated_at, media.content_hash, media.last_checked
+FROM media
+         JOIN media_genres
+              ON media.id = media_genres.media_id
+         JOIN genres
+              ON genres.id = media_genres.genre_id
+WHERE genres.name = $1
+ORDER BY media.id
+LIMIT $2 OFFSET $3
+`
+
+type ListMediaByGenreParams struct {
+	Name   string
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListMediaByGenre(ctx context.Context, arg ListMediaByGenreParams) ([]Medium, error) {
+	rows, err := q.db.Query(ctx, listMediaByGenre, arg.Name, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Titles,
+			&i.Type,
+			&i.Format,
+			&i.Status,
+			&i.Season,
+			&i.SeasonYear,
+			&i.Episodes,
+			&i.Chapters,
+			&i.Volumes,
+			&i.CoverImage,
+			&i.Genres,
+			&i.AverageScore,
+			&i.Studios,
+			&i.IsAdult,
+			&i.LastUpdated,
+			&i.AnilistUpdatedAt,
+			&i.ContentHash,
+			&i.LastChecked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByStudio = `-- name: ListMediaByStudio :many
+SELECT media.id, media.titles, media.type, media.format, media.status, media.season, media.season_year, media.episodes, media.chapters, media.volumes, media.cover_image, media.genres, media.average_score, media.studios, media.is_adult, media.last_updated, media.anilist_updated_at, media.content_hash, media.last_checked
+FROM media
+         JOIN media_studios
+              ON media.id = media_studios.media_id
+WHERE media_studios.studio_id = $1
+ORDER BY media.id
+LIMIT $2 OFFSET $3
+`
+
+type ListMediaByStudioParams struct {
+	StudioID int32
+	Limit    int32
+	Offset   int32
+}
+
+func (q *Queries) ListMediaByStudio(ctx context.Context, arg ListMediaByStudioParams) ([]Medium, error) {
+	rows, err := q.db.Query(ctx, listMediaByStudio, arg.StudioID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Titles,
+			&i.Type,
+			&i.Format,
+			&i.Status,
+			&i.Season,
+			&i.SeasonYear,
+			&i.Episodes,
+			&i.Chapters,
+			&i.Volumes,
+			&i.CoverImage,
+			&i.Genres,
+			&i.AverageScore,
+			&i.Studios,
+			&i.IsAdult,
+			&i.LastUpdated,
+			&i.AnilistUpdatedAt,
+			&i.ContentHash,
+			&i.LastChecked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByTag = `-- name: ListMediaByTag :many
+SELECT media.id, media.titles, media.type, media.format, media.status, media.season, media.season_year, media.episodes, media.chapters, media.volumes, media.cover_image, media.genres, media.average_score, media.studios, media.is_adult, media.last_updated, media.anilist_updated_at, media.content_hash, media.last_checked, media_tags.rank, media_tags.is_media_spoiler
+FROM media
+         JOIN media_tags
+              ON media.id = media_tags.media_id
+         JOIN tags
+              ON tags.id = media_tags.tag_id
+WHERE tags.name = $1
+  AND media_tags.rank >= $2
+ORDER BY media_tags.rank DESC, media.id
+LIMIT $3 OFFSET $4
+`
+
+type ListMediaByTagParams struct {
+	Name   string
+	Rank   int32
+	Limit  int32
+	Offset int32
+}
+
+type ListMediaByTagRow struct {
+	ID               int32
+	Titles           string
+	Type             NullMediaType
+	Format           pgtype.Text
+	Status           string
+	Season           pgtype.Text
+	SeasonYear       pgtype.Int4
+	Episodes         pgtype.Int4
+	Chapters         pgtype.Int4
+	Volumes          pgtype.Int4
+	CoverImage       pgtype.Text
+	Genres           []string
+	AverageScore     pgtype.Int4
+	Studios          []string
+	IsAdult          pgtype.Bool
+	LastUpdated      pgtype.Timestamptz
+	AnilistUpdatedAt pgtype.Int8
+	ContentHash      pgtype.Text
+	LastChecked      pgtype.Timestamptz
+	Rank             int32
+	IsMediaSpoiler   bool
+}
+
+func (q *Queries) ListMediaByTag(ctx context.Context, arg ListMediaByTagParams) ([]ListMediaByTagRow, error) {
+	rows, err := q.db.Query(ctx, listMediaByTag,
+		arg.Name,
+		arg.Rank,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMediaByTagRow
+	for rows.Next() {
+		var i ListMediaByTagRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Titles,
+			&i.Type,
+			&i.Format,
+			&i.Status,
+			&i.Season,
+			&i.SeasonYear,
+			&i.Episodes,
+			&i.Chapters,
+			&i.Volumes,
+			&i.CoverImage,
+			&i.Genres,
+			&i.AverageScore,
+			&i.Studios,
+			&i.IsAdult,
+			&i.LastUpdated,
+			&i.AnilistUpdatedAt,
+			&i.ContentHash,
+			&i.LastChecked,
+			&i.Rank,
+			&i.IsMediaSpoiler,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaCharacters = `-- name: ListMediaCharacters :many
+SELECT characters.id, characters.name_full, characters.name_native, characters.image, media_characters.role,
+       staff.id AS voice_actor_id, staff.name_full AS voice_actor_name_full, staff.name_native AS voice_actor_name_native
+FROM media_characters
+         JOIN characters
+              ON characters.id = media_characters.character_id
+         LEFT JOIN character_voice_actors
+                   ON character_voice_actors.media_id = media_characters.media_id
+                       AND character_voice_actors.character_id = media_characters.character_id
+         LEFT JOIN staff
+                   ON staff.id = character_voice_actors.staff_id
+WHERE media_characters.media_id = $1
+ORDER BY media_characters.position, staff.id
+`
+
+type ListMediaCharactersRow struct {
+	ID                   int32
+	NameFull             pgtype.Text
+	NameNative           pgtype.Text
+	Image                pgtype.Text
+	Role                 string
+	VoiceActorID         pgtype.Int4
+	VoiceActorNameFull   pgtype.Text
+	VoiceActorNameNative pgtype.Text
+}
+
+func (q *Queries) ListMediaCharacters(ctx context.Context, mediaID int32) ([]ListMediaCharactersRow, error) {
+	rows, err := q.db.Query(ctx, listMediaCharacters, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMediaCharactersRow
+	for rows.Next() {
+		var i ListMediaCharactersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NameFull,
+			&i.NameNative,
+			&i.Image,
+			&i.Role,
+			&i.VoiceActorID,
+			&i.VoiceActorNameFull,
+			&i.VoiceActorNameNative,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaForCreditSync = `-- name: ListMediaForCreditSync :many
+SELECT media.id
+FROM media
+         LEFT JOIN credit_syncs
+                   ON credit_syncs.media_id = media.id
+                       AND credit_syncs.credit = $1
+ORDER BY credit_syncs.synced_at NULLS FIRST, media.id
+LIMIT $2
+`
+
+type ListMediaForCreditSyncParams struct {
+	Credit string
+	Limit  int32
+}
+
+func (q *Queries) ListMediaForCreditSync(ctx context.Context, arg ListMediaForCreditSyncParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listMediaForCreditSync, arg.Credit, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaStaff = `-- name: ListMediaStaff :many
+SELECT staff.id, staff.name_full, staff.name_native, staff.image, media_staff.role
+FROM media_staff
+         JOIN staff
+              ON staff.id = media_staff.staff_id
+WHERE media_staff.media_id = $1
+ORDER BY media_staff.position
+`
+
+type ListMediaStaffRow struct {
+	ID         int32
+	NameFull   pgtype.Text
+	NameNative pgtype.Text
+	Image      pgtype.Text
+	Role       string
+}
+
+func (q *Queries) ListMediaStaff(ctx context.Context, mediaID int32) ([]ListMediaStaffRow, error) {
+	rows, err := q.db.Query(ctx, listMediaStaff, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMediaStaffRow
+	for rows.Next() {
+		var i ListMediaStaffRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NameFull,
+			&i.NameNative,
+			&i.Image,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentSyncRuns = `-- name: ListRecentSyncRuns :many
+SELECT id, mode, status, last_completed_page, failed_pages, failed_ids, started_at, updated_at, finished_at, high_water_mark
+FROM sync_runs
+ORDER BY started_at DESC
+LIMIT $1
+`
+
+func (q *Queries) ListRecentSyncRuns(ctx context.Context, limit int32) ([]SyncRun, error) {
+	rows, err := q.db.Query(ctx, listRecentSyncRuns, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SyncRun
+	for rows.Next() {
+		var i SyncRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.Mode,
+			&i.Status,
+			&i.LastCompletedPage,
+			&i.FailedPages,
+			&i.FailedIds,
+			&i.StartedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.HighWaterMark,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRescheduledEpisodes = `-- name: ListRescheduledEpisodes :many
+SELECT media_id, episode, airing_at, previous_airing_at, rescheduled_at
+FROM airing_episodes
+WHERE rescheduled_at >= $1
+ORDER BY rescheduled_at DESC
+`
+
+type ListRescheduledEpisodesRow struct {
+	MediaID          int32
+	Episode          int32
+	AiringAt         int64
+	PreviousAiringAt pgtype.Int8
+	RescheduledAt    pgtype.Timestamptz
+}
+
+func (q *Queries) ListRescheduledEpisodes(ctx context.Context, rescheduledAt pgtype.Timestamptz) ([]ListRescheduledEpisodesRow, error) {
+	rows, err := q.db.Query(ctx, listRescheduledEpisodes, rescheduledAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRescheduledEpisodesRow
+	for rows.Next() {
+		var i ListRescheduledEpisodesRow
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Episode,
+			&i.AiringAt,
+			&i.PreviousAiringAt,
+			&i.RescheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStaffCredits = `-- name: ListStaffCredits :many
+SELECT media.id, media.titles, media.type, media.format, media.season_year, media_staff.role
+FROM media_staff
+         JOIN media
+              ON media.id = media_staff.media_id
+WHERE media_staff.staff_id = $1
+ORDER BY media.season_year DESC NULLS LAST, media.id
+`
+
+type ListStaffCreditsRow struct {
+	ID         int32
+	Titles     string
+	Type       NullMediaType
+	Format     pgtype.Text
+	SeasonYear pgtype.Int4
+	Role       string
+}
+
+func (q *Queries) ListStaffCredits(ctx context.Context, staffID int32) ([]ListStaffCreditsRow, error) {
+	rows, err := q.db.Query(ctx, listStaffCredits, staffID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListStaffCreditsRow
+	for rows.Next() {
+		var i ListStaffCreditsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Titles,
+			&i.Type,
+			&i.Format,
+			&i.SeasonYear,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncLockHolders = `-- name: ListSyncLockHolders :many
+SELECT sync_locks.name, sync_locks.holder, sync_locks.command, sync_locks.backend_pid, sync_locks.acquired_at,
+       EXISTS (SELECT 1
+               FROM pg_locks
+               WHERE pg_locks.locktype = 'advisory'
+                 AND pg_locks.classid = 7411
+                 AND pg_locks.pid = sync_locks.backend_pid
+                 AND pg_locks.granted) AS held
+FROM sync_locks
+ORDER BY name
+`
+
+type ListSyncLockHoldersRow struct {
+	Name       string
+	Holder     string
+	Command    string
+	BackendPid int32
+	AcquiredAt pgtype.Timestamptz
+	Held       bool
+}
+
+// held is false for rows left behind by a holder whose session is gone
+func (q *Queries) ListSyncLockHolders(ctx context.Context) ([]ListSyncLockHoldersRow, error) {
+	rows, err := q.db.Query(ctx, listSyncLockHolders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSyncLockHoldersRow
+	for rows.Next() {
+		var i ListSyncLockHoldersRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Holder,
+			&i.Command,
+			&i.BackendPid,
+			&i.AcquiredAt,
+			&i.Held,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWatchlistUsers = `-- name: ListWatchlistUsers :many
+SELECT DISTINCT user_id
+FROM watchlist
+WHERE user_id IS NOT NULL
+ORDER BY user_id
+`
+
+func (q *Queries) ListWatchlistUsers(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listWatchlistUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var user_id pgtype.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markCreditSynced = `-- name: MarkCreditSynced :exec
+INSERT INTO credit_syncs (media_id, credit, synced_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (media_id, credit) DO UPDATE
+SET synced_at = NOW()
+`
+
+type MarkCreditSyncedParams struct {
+	MediaID int32
+	Credit  string
+}
+
+func (q *Queries) MarkCreditSynced(ctx context.Context, arg MarkCreditSyncedParams) error {
+	_, err := q.db.Exec(ctx, markCreditSynced, arg.MediaID, arg.Credit)
+	return err
+}
+
+const markMediaChecked = `-- name: MarkMediaChecked :execrows
+UPDATE media
+SET last_checked = NOW()
+WHERE id = $1
+  AND content_hash = $2
+`
+
+type MarkMediaCheckedParams struct {
+	ID          int32
+	ContentHash pgtype.Text
+}
+
+func (q *Queries) MarkMediaChecked(ctx context.Context, arg MarkMediaCheckedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markMediaChecked, arg.ID, arg.ContentHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const pruneMediaSnapshots = `-- name: PruneMediaSnapshots :execrows
+DELETE
+FROM media_snapshots
+WHERE (media_id, snapshot_date) IN (
+    SELECT ranked.media_id, ranked.snapshot_date
+    FROM (
+        SELECT media_id,
+               snapshot_date,
+               ROW_NUMBER() OVER (
+                   PARTITION BY media_id, DATE_TRUNC(
+                       CASE WHEN snapshot_date < CURRENT_DATE - $1::INTEGER THEN 'month' ELSE 'week' END,
+                       snapshot_date)
+                   ORDER BY snapshot_date DESC
+               ) AS position
+        FROM media_snapshots
+        WHERE snapshot_date < CURRENT_DATE - $2::INTEGER
+    ) ranked
+    WHERE ranked.position > 1
+)
+`
+
+type PruneMediaSnapshotsParams struct {
+	WeeklyDays int32
+	DailyDays  int32
+}
+
+func (q *Queries) PruneMediaSnapshots(ctx context.Context, arg PruneMediaSnapshotsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, pruneMediaSnapshots, arg.WeeklyDays, arg.DailyDays)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const putAiredEpisodes = `-- name: PutAiredEpisodes :execrows
+INSERT INTO airing_episodes (media_id, episode, airing_at)
+SELECT aired.media_id, aired.episode, aired.airing_at
+FROM UNNEST($1::INTEGER[], $2::INTEGER[],
+            $3::BIGINT[]) AS aired (media_id, episode, airing_at)
+WHERE EXISTS (SELECT 1 FROM media WHERE media.id = aired.media_id)
+ON CONFLICT (media_id, episode) DO UPDATE
+SET airing_at          = EXCLUDED.airing_at,
+    previous_airing_at = airing_episodes.airing_at,
+    rescheduled_at     = NOW(),
+    reschedule_count   = airing_episodes.reschedule_count + 1,
+    updated_at         = NOW()
+WHERE airing_episodes.airing_at IS DISTINCT FROM EXCLUDED.airing_at
+`
+
+type PutAiredEpisodesParams struct {
+	MediaIds  []int32
+	Episodes  []int32
+	AiringAts []int64
+}
+
+// final air times of recently aired episodes, media we do not store are skipped
+func (q *Queries) PutAiredEpisodes(ctx context.Context, arg PutAiredEpisodesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, putAiredEpisodes, arg.MediaIds, arg.Episodes, arg.AiringAts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const putMedia = `-- name: PutMedia :exec
 INSERT INTO media (id,
                    titles,
@@ -28,7 +1361,10 @@ INSERT INTO media (id,
                    average_score,
                    studios,
                    is_adult,
-                   last_updated)
+                   anilist_updated_at,
+                   content_hash,
+                   last_updated,
+                   last_checked)
 VALUES ($1,
         ROW($2, $3, $4)::titles,
         $5,
@@ -44,6 +1380,9 @@ VALUES ($1,
         $15,
         $16,
         $17,
+        $18,
+        $19,
+        NOW(),
         NOW()
         )
 ON CONFLICT (id) DO UPDATE
@@ -61,27 +1400,32 @@ genres        = $14,
 average_score = $15,
 studios       = $16,
 is_adult      = $17,
-last_updated = NOW()
+anilist_updated_at = $18,
+content_hash = $19,
+last_updated = NOW(),
+last_checked = NOW()
 `
 
 type PutMediaParams struct {
-	ID           int32
-	Column2      string
-	Column3      string
-	Column4      string
-	Type         NullMediaType
-	Format       pgtype.Text
-	Status       string
-	Season       pgtype.Text
-	SeasonYear   pgtype.Int4
-	Episodes     pgtype.Int4
-	Chapters     pgtype.Int4
-	Volumes      pgtype.Int4
-	CoverImage   pgtype.Text
-	Genres       []string
-	AverageScore pgtype.Int4
-	Studios      []string
-	IsAdult      pgtype.Bool
+	ID               int32
+	Column2          string
+	Column3          string
+	Column4          string
+	Type             NullMediaType
+	Format           pgtype.Text
+	Status           string
+	Season           pgtype.Text
+	SeasonYear       pgtype.Int4
+	Episodes         pgtype.Int4
+	Chapters         pgtype.Int4
+	Volumes          pgtype.Int4
+	CoverImage       pgtype.Text
+	Genres           []string
+	AverageScore     pgtype.Int4
+	Studios          []string
+	IsAdult          pgtype.Bool
+	AnilistUpdatedAt pgtype.Int8
+	ContentHash      pgtype.Text
 }
 
 func (q *Queries) PutMedia(ctx context.Context, arg PutMediaParams) error {
@@ -103,6 +1447,8 @@ func (q *Queries) PutMedia(ctx context.Context, arg PutMediaParams) error {
 		arg.AverageScore,
 		arg.Studios,
 		arg.IsAdult,
+		arg.AnilistUpdatedAt,
+		arg.ContentHash,
 	)
 	return err
 }
@@ -303,3 +1649,495 @@ func (q *Queries) QueryLowPrioMedia(ctx context.Context) ([]int32, error) {
 	}
 	return items, nil
 }
+
+const recordFailedMedia = `-- name: RecordFailedMedia :exec
+INSERT INTO failed_media (media_id, error, payload)
+VALUES ($1, $2, $3)
+ON CONFLICT (media_id) DO UPDATE
+SET error          = $2,
+    payload        = $3,
+    attempts       = failed_media.attempts + 1,
+    last_failed_at = NOW()
+`
+
+type RecordFailedMediaParams struct {
+	MediaID int32
+	Error   string
+	Payload []byte
+}
+
+func (q *Queries) RecordFailedMedia(ctx context.Context, arg RecordFailedMediaParams) error {
+	_, err := q.db.Exec(ctx, recordFailedMedia, arg.MediaID, arg.Error, arg.Payload)
+	return err
+}
+
+const recordMediaHistory = `-- name: RecordMediaHistory :exec
+INSERT INTO media_history (media_id, field, old_value, new_value, sync_run_id)
+SELECT media.id, change.field, change.old_value, change.new_value, $1::BIGINT
+FROM media
+         LEFT JOIN media_details
+                   ON media.id = media_details.id
+         CROSS JOIN LATERAL (VALUES ('status', media.status, $2::TEXT),
+                                    ('episodes', media.episodes::TEXT, $3::TEXT),
+                                    ('chapters', media.chapters::TEXT, $4::TEXT),
+                                    ('volumes', media.volumes::TEXT, $5::TEXT),
+                                    ('average_score', media.average_score::TEXT, $6::TEXT),
+                                    ('popularity', media_details.popularity::TEXT, $7::TEXT),
+                                    ('favourites', media_details.favourites::TEXT, $8::TEXT))
+    AS change (field, old_value, new_value)
+WHERE media.id = $9
+  AND change.old_value IS DISTINCT FROM change.new_value
+`
+
+type RecordMediaHistoryParams struct {
+	SyncRunID    pgtype.Int8
+	Status       string
+	Episodes     pgtype.Text
+	Chapters     pgtype.Text
+	Volumes      pgtype.Text
+	AverageScore pgtype.Text
+	Popularity   pgtype.Text
+	Favourites   pgtype.Text
+	ID           int32
+}
+
+func (q *Queries) RecordMediaHistory(ctx context.Context, arg RecordMediaHistoryParams) error {
+	_, err := q.db.Exec(ctx, recordMediaHistory,
+		arg.SyncRunID,
+		arg.Status,
+		arg.Episodes,
+		arg.Chapters,
+		arg.Volumes,
+		arg.AverageScore,
+		arg.Popularity,
+		arg.Favourites,
+		arg.ID,
+	)
+	return err
+}
+
+const recordSyncLockHolder = `-- name: RecordSyncLockHolder :exec
+INSERT INTO sync_locks (name, holder, command, backend_pid)
+VALUES ($1, $2, $3, pg_backend_pid())
+ON CONFLICT (name) DO UPDATE
+    SET holder      = excluded.holder,
+        command     = excluded.command,
+        backend_pid = excluded.backend_pid,
+        acquired_at = NOW()
+`
+
+type RecordSyncLockHolderParams struct {
+	Name    string
+	Holder  string
+	Command string
+}
+
+func (q *Queries) RecordSyncLockHolder(ctx context.Context, arg RecordSyncLockHolderParams) error {
+	_, err := q.db.Exec(ctx, recordSyncLockHolder, arg.Name, arg.Holder, arg.Command)
+	return err
+}
+
+const releaseSyncLock = `-- name: ReleaseSyncLock :one
+SELECT pg_advisory_unlock(7411, hashtext($1::TEXT))
+`
+
+func (q *Queries) ReleaseSyncLock(ctx context.Context, name string) (bool, error) {
+	row := q.db.QueryRow(ctx, releaseSyncLock, name)
+	var pg_advisory_unlock bool
+	err := row.Scan(&pg_advisory_unlock)
+	return pg_advisory_unlock, err
+}
+
+const reopenSyncRun = `-- name: ReopenSyncRun :exec
+UPDATE sync_runs
+SET status      = 'running',
+    finished_at = NULL,
+    updated_at  = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) ReopenSyncRun(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, reopenSyncRun, id)
+	return err
+}
+
+const setAiringEpisodes = `-- name: SetAiringEpisodes :exec
+WITH removed AS (
+    DELETE
+    FROM airing_episodes
+    WHERE airing_episodes.media_id = $1::INTEGER
+      AND airing_episodes.airing_at > EXTRACT(EPOCH FROM NOW())::BIGINT
+      AND episode <> ALL ($2::INTEGER[])
+)
+INSERT INTO airing_episodes (media_id, episode, airing_at)
+SELECT $1::INTEGER, schedule.episode, schedule.airing_at
+FROM UNNEST($2::INTEGER[], $3::BIGINT[]) AS schedule (episode, airing_at)
+ON CONFLICT (media_id, episode) DO UPDATE
+SET airing_at          = EXCLUDED.airing_at,
+    previous_airing_at = airing_episodes.airing_at,
+    rescheduled_at     = NOW(),
+    reschedule_count   = airing_episodes.reschedule_count + 1,
+    updated_at         = NOW()
+WHERE airing_episodes.airing_at IS DISTINCT FROM EXCLUDED.airing_at
+`
+
+type SetAiringEpisodesParams struct {
+	MediaID   int32
+	Episodes  []int32
+	AiringAts []int64
+}
+
+// upcoming episodes AniList no longer lists are dropped, aired ones are kept as history
+func (q *Queries) SetAiringEpisodes(ctx context.Context, arg SetAiringEpisodesParams) error {
+	_, err := q.db.Exec(ctx, setAiringEpisodes, arg.MediaID, arg.Episodes, arg.AiringAts)
+	return err
+}
+
+const setCharacterVoiceActors = `-- name: SetCharacterVoiceActors :exec
+WITH removed AS (
+    DELETE
+    FROM character_voice_actors
+    WHERE character_voice_actors.media_id = $1::INTEGER
+      AND character_voice_actors.language = $2::TEXT
+      AND (character_id, staff_id) NOT IN
+          (SELECT * FROM UNNEST($3::INTEGER[], $4::INTEGER[]))
+)
+INSERT INTO character_voice_actors (media_id, character_id, staff_id, language)
+SELECT $1::INTEGER, link.character_id, link.staff_id, $2::TEXT
+FROM UNNEST($3::INTEGER[], $4::INTEGER[]) AS link (character_id, staff_id)
+ON CONFLICT DO NOTHING
+`
+
+type SetCharacterVoiceActorsParams struct {
+	MediaID      int32
+	Language     string
+	CharacterIds []int32
+	StaffIds     []int32
+}
+
+func (q *Queries) SetCharacterVoiceActors(ctx context.Context, arg SetCharacterVoiceActorsParams) error {
+	_, err := q.db.Exec(ctx, setCharacterVoiceActors,
+		arg.MediaID,
+		arg.Language,
+		arg.CharacterIds,
+		arg.StaffIds,
+	)
+	return err
+}
+
+const setMediaCharacters = `-- name: SetMediaCharacters :exec
+WITH removed AS (
+    DELETE
+    FROM media_characters
+    WHERE media_characters.media_id = $1::INTEGER
+      AND character_id <> ALL ($2::INTEGER[])
+)
+INSERT INTO media_characters (media_id, character_id, role, position)
+SELECT $1::INTEGER, link.character_id, link.role, link.position
+FROM UNNEST($2::INTEGER[], $3::TEXT[]) WITH ORDINALITY
+         AS link (character_id, role, position)
+ON CONFLICT (media_id, character_id) DO UPDATE
+SET role     = EXCLUDED.role,
+    position = EXCLUDED.position
+WHERE (media_characters.role, media_characters.position) IS DISTINCT FROM (EXCLUDED.role, EXCLUDED.position)
+`
+
+type SetMediaCharactersParams struct {
+	MediaID      int32
+	CharacterIds []int32
+	Roles        []string
+}
+
+func (q *Queries) SetMediaCharacters(ctx context.Context, arg SetMediaCharactersParams) error {
+	_, err := q.db.Exec(ctx, setMediaCharacters, arg.MediaID, arg.CharacterIds, arg.Roles)
+	return err
+}
+
+const setMediaGenres = `-- name: SetMediaGenres :exec
+WITH removed AS (
+    DELETE
+    FROM media_genres
+    WHERE media_genres.media_id = $1::INTEGER
+      AND genre_id NOT IN (SELECT id FROM genres WHERE name = ANY ($2::TEXT[]))
+)
+INSERT INTO media_genres (media_id, genre_id)
+SELECT $1::INTEGER, id
+FROM genres
+WHERE name = ANY ($2::TEXT[])
+ON CONFLICT DO NOTHING
+`
+
+type SetMediaGenresParams struct {
+	MediaID int32
+	Names   []string
+}
+
+func (q *Queries) SetMediaGenres(ctx context.Context, arg SetMediaGenresParams) error {
+	_, err := q.db.Exec(ctx, setMediaGenres, arg.MediaID, arg.Names)
+	return err
+}
+
+const setMediaRelations = `-- name: SetMediaRelations :exec
+WITH removed AS (
+    DELETE
+    FROM media_relations
+    WHERE media_relations.media_id = $1::INTEGER
+      AND related_id <> ALL ($2::INTEGER[])
+)
+INSERT INTO media_relations (media_id, related_id, relation_type)
+SELECT $1::INTEGER, link.related_id, link.relation_type
+FROM UNNEST($2::INTEGER[], $3::TEXT[]) AS link (related_id, relation_type)
+ON CONFLICT (media_id, related_id) DO UPDATE
+SET relation_type = EXCLUDED.relation_type
+WHERE media_relations.relation_type IS DISTINCT FROM EXCLUDED.relation_type
+`
+
+type SetMediaRelationsParams struct {
+	MediaID       int32
+	RelatedIds    []int32
+	RelationTypes []string
+}
+
+func (q *Queries) SetMediaRelations(ctx context.Context, arg SetMediaRelationsParams) error {
+	_, err := q.db.Exec(ctx, setMediaRelations, arg.MediaID, arg.RelatedIds, arg.RelationTypes)
+	return err
+}
+
+const setMediaStaff = `-- name: SetMediaStaff :exec
+WITH removed AS (
+    DELETE
+    FROM media_staff
+    WHERE media_staff.media_id = $1::INTEGER
+      AND (staff_id, role) NOT IN (SELECT * FROM UNNEST($2::INTEGER[], $3::TEXT[]))
+)
+INSERT INTO media_staff (media_id, staff_id, role, position)
+SELECT $1::INTEGER, link.staff_id, link.role, link.position
+FROM UNNEST($2::INTEGER[], $3::TEXT[]) WITH ORDINALITY
+         AS link (staff_id, role, position)
+ON CONFLICT (media_id, staff_id, role) DO UPDATE
+SET position = EXCLUDED.position
+WHERE media_staff.position IS DISTINCT FROM EXCLUDED.position
+`
+
+type SetMediaStaffParams struct {
+	MediaID  int32
+	StaffIds []int32
+	Roles    []string
+}
+
+func (q *Queries) SetMediaStaff(ctx context.Context, arg SetMediaStaffParams) error {
+	_, err := q.db.Exec(ctx, setMediaStaff, arg.MediaID, arg.StaffIds, arg.Roles)
+	return err
+}
+
+const setMediaStudios = `-- name: SetMediaStudios :exec
+WITH upserted AS (
+    INSERT INTO studios (id, name, is_animation_studio)
+    SELECT *
+    FROM UNNEST($1::INTEGER[], $2::TEXT[], $3::BOOLEAN[])
+    ON CONFLICT (id) DO UPDATE
+    SET name                = EXCLUDED.name,
+        is_animation_studio = EXCLUDED.is_animation_studio
+    WHERE studios.name IS DISTINCT FROM EXCLUDED.name
+       OR studios.is_animation_studio IS DISTINCT FROM EXCLUDED.is_animation_studio
+),
+removed AS (
+    DELETE
+    FROM media_studios
+    WHERE media_studios.media_id = $4::INTEGER
+      AND studio_id <> ALL ($1::INTEGER[])
+)
+INSERT INTO media_studios (media_id, studio_id)
+SELECT $4::INTEGER, UNNEST($1::INTEGER[])
+ON CONFLICT DO NOTHING
+`
+
+type SetMediaStudiosParams struct {
+	StudioIds          []int32
+	Names              []string
+	IsAnimationStudios []bool
+	MediaID            int32
+}
+
+func (q *Queries) SetMediaStudios(ctx context.Context, arg SetMediaStudiosParams) error {
+	_, err := q.db.Exec(ctx, setMediaStudios,
+		arg.StudioIds,
+		arg.Names,
+		arg.IsAnimationStudios,
+		arg.MediaID,
+	)
+	return err
+}
+
+const setMediaTags = `-- name: SetMediaTags :exec
+WITH upserted AS (
+    INSERT INTO tags (id, name, category, is_adult)
+    SELECT *
+    FROM UNNEST($1::INTEGER[], $2::TEXT[], $3::TEXT[],
+                $4::BOOLEAN[])
+    ON CONFLICT (id) DO UPDATE
+    SET name     = EXCLUDED.name,
+        category = EXCLUDED.category,
+        is_adult = EXCLUDED.is_adult
+    WHERE (tags.name, tags.category, tags.is_adult) IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.category, EXCLUDED.is_adult)
+),
+removed AS (
+    DELETE
+    FROM media_tags
+    WHERE media_tags.media_id = $5::INTEGER
+      AND tag_id <> ALL ($1::INTEGER[])
+)
+INSERT INTO media_tags (media_id, tag_id, rank, is_media_spoiler)
+SELECT $5::INTEGER, link.tag_id, link.rank, link.is_media_spoiler
+FROM UNNEST($1::INTEGER[], $6::INTEGER[], $7::BOOLEAN[])
+         AS link (tag_id, rank, is_media_spoiler)
+ON CONFLICT (media_id, tag_id) DO UPDATE
+SET rank             = EXCLUDED.rank,
+    is_media_spoiler = EXCLUDED.is_media_spoiler
+WHERE (media_tags.rank, media_tags.is_media_spoiler) IS DISTINCT FROM (EXCLUDED.rank, EXCLUDED.is_media_spoiler)
+`
+
+type SetMediaTagsParams struct {
+	TagIds          []int32
+	Names           []string
+	Categories      []string
+	IsAdults        []bool
+	MediaID         int32
+	Ranks           []int32
+	IsMediaSpoilers []bool
+}
+
+func (q *Queries) SetMediaTags(ctx context.Context, arg SetMediaTagsParams) error {
+	_, err := q.db.Exec(ctx, setMediaTags,
+		arg.TagIds,
+		arg.Names,
+		arg.Categories,
+		arg.IsAdults,
+		arg.MediaID,
+		arg.Ranks,
+		arg.IsMediaSpoilers,
+	)
+	return err
+}
+
+const setSyncRunHighWaterMark = `-- name: SetSyncRunHighWaterMark :exec
+UPDATE sync_runs
+SET high_water_mark = $2,
+    updated_at      = NOW()
+WHERE id = $1
+`
+
+type SetSyncRunHighWaterMarkParams struct {
+	ID            int64
+	HighWaterMark pgtype.Int8
+}
+
+func (q *Queries) SetSyncRunHighWaterMark(ctx context.Context, arg SetSyncRunHighWaterMarkParams) error {
+	_, err := q.db.Exec(ctx, setSyncRunHighWaterMark, arg.ID, arg.HighWaterMark)
+	return err
+}
+
+const snapshotMedia = `-- name: SnapshotMedia :execrows
+INSERT INTO media_snapshots (media_id, snapshot_date, average_score, popularity, trending, favourites)
+SELECT media.id, CURRENT_DATE, media.average_score, media_details.popularity, media_details.trending, media_details.favourites
+FROM media
+         JOIN media_details
+              ON media.id = media_details.id
+WHERE media.id = ANY ($1::INTEGER[])
+ON CONFLICT (media_id, snapshot_date) DO UPDATE
+SET average_score = EXCLUDED.average_score,
+    popularity    = EXCLUDED.popularity,
+    trending      = EXCLUDED.trending,
+    favourites    = EXCLUDED.favourites
+`
+
+func (q *Queries) SnapshotMedia(ctx context.Context, dollar_1 []int32) (int64, error) {
+	result, err := q.db.Exec(ctx, snapshotMedia, dollar_1)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const tryAcquireSyncLock = `-- name: TryAcquireSyncLock :one
+SELECT pg_try_advisory_lock(7411, hashtext($1::TEXT))
+`
+
+// 7411 namespaces our advisory locks away from anything else on the database
+func (q *Queries) TryAcquireSyncLock(ctx context.Context, name string) (bool, error) {
+	row := q.db.QueryRow(ctx, tryAcquireSyncLock, name)
+	var pg_try_advisory_lock bool
+	err := row.Scan(&pg_try_advisory_lock)
+	return pg_try_advisory_lock, err
+}
+
+const upsertCharacters = `-- name: UpsertCharacters :exec
+INSERT INTO characters (id, name_full, name_native, image)
+SELECT input.id, NULLIF(input.name_full, ''), NULLIF(input.name_native, ''), NULLIF(input.image, '')
+FROM UNNEST($1::INTEGER[], $2::TEXT[], $3::TEXT[],
+            $4::TEXT[]) AS input (id, name_full, name_native, image)
+ON CONFLICT (id) DO UPDATE
+SET name_full   = EXCLUDED.name_full,
+    name_native = EXCLUDED.name_native,
+    image       = EXCLUDED.image
+WHERE (characters.name_full, characters.name_native, characters.image) IS DISTINCT FROM
+      (EXCLUDED.name_full, EXCLUDED.name_native, EXCLUDED.image)
+`
+
+type UpsertCharactersParams struct {
+	Ids         []int32
+	NameFulls   []string
+	NameNatives []string
+	Images      []string
+}
+
+func (q *Queries) UpsertCharacters(ctx context.Context, arg UpsertCharactersParams) error {
+	_, err := q.db.Exec(ctx, upsertCharacters,
+		arg.Ids,
+		arg.NameFulls,
+		arg.NameNatives,
+		arg.Images,
+	)
+	return err
+}
+
+const upsertGenres = `-- name: UpsertGenres :exec
+INSERT INTO genres (name)
+SELECT UNNEST($1::TEXT[])
+ON CONFLICT (name) DO NOTHING
+`
+
+func (q *Queries) UpsertGenres(ctx context.Context, names []string) error {
+	_, err := q.db.Exec(ctx, upsertGenres, names)
+	return err
+}
+
+const upsertStaff = `-- name: UpsertStaff :exec
+INSERT INTO staff (id, name_full, name_native, image)
+SELECT input.id, NULLIF(input.name_full, ''), NULLIF(input.name_native, ''), NULLIF(input.image, '')
+FROM UNNEST($1::INTEGER[], $2::TEXT[], $3::TEXT[],
+            $4::TEXT[]) AS input (id, name_full, name_native, image)
+ON CONFLICT (id) DO UPDATE
+SET name_full   = EXCLUDED.name_full,
+    name_native = EXCLUDED.name_native,
+    image       = COALESCE(EXCLUDED.image, staff.image)
+WHERE (staff.name_full, staff.name_native) IS DISTINCT FROM (EXCLUDED.name_full, EXCLUDED.name_native)
+   OR (EXCLUDED.image IS NOT NULL AND staff.image IS DISTINCT FROM EXCLUDED.image)
+`
+
+type UpsertStaffParams struct {
+	Ids         []int32
+	NameFulls   []string
+	NameNatives []string
+	Images      []string
+}
+
+func (q *Queries) UpsertStaff(ctx context.Context, arg UpsertStaffParams) error {
+	_, err := q.db.Exec(ctx, upsertStaff,
+		arg.Ids,
+		arg.NameFulls,
+		arg.NameNatives,
+		arg.Images,
+	)
+	return err
+}
@@ -0,0 +1,67 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AiredWindowDays is how far back UpdateAiredEpisodes corrects the air times of aired episodes
+var AiredWindowDays = 7
+
+// UpdateAiredEpisodes stores the final air time of every episode that aired in the last
+// AiredWindowDays, mediaFields only sees upcoming episodes so a delay announced on the day
+// would otherwise never reach the stored row
+func UpdateAiredEpisodes(ctx context.Context, pool *pgxpool.Pool, url string) error {
+	q := database.New(pool)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+
+	now := time.Now()
+	variables := map[string]interface{}{
+		"from": now.AddDate(0, 0, -AiredWindowDays).Unix(),
+		"to":   now.Unix(),
+	}
+
+	var stored, moved int64
+	for page := 1; ; page++ {
+		variables["page"] = page
+
+		var response RecentlyAiredResponse
+		err := PageRetryPolicy.Do(ctx, func(attempt int) error {
+			response = RecentlyAiredResponse{}
+			_, err := handler.Query(ctx, RecentlyAiredEpisodes, variables, &response)
+			return err
+		}, IsRetryable)
+		if err != nil {
+			return fmt.Errorf("aired episodes page %d: %w", page, err)
+		}
+
+		params := database.PutAiredEpisodesParams{
+			MediaIds:  []int32{},
+			Episodes:  []int32{},
+			AiringAts: []int64{},
+		}
+		for _, episode := range response.Page.AiringSchedules {
+			params.MediaIds = append(params.MediaIds, int32(episode.MediaID))
+			params.Episodes = append(params.Episodes, int32(episode.Episode))
+			params.AiringAts = append(params.AiringAts, int64(episode.AiringAt))
+		}
+
+		changed, err := q.PutAiredEpisodes(ctx, params)
+		if err != nil {
+			return err
+		}
+		stored += int64(len(params.MediaIds))
+		moved += changed
+
+		if !response.Page.PageInfo.HasNextPage {
+			break
+		}
+	}
+
+	fmt.Printf("Checked %d episodes aired in the last %d days, %d were new or moved\n", stored, AiredWindowDays, moved)
+	return nil
+}
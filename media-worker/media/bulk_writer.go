@@ -0,0 +1,581 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"media-worker/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The staging tables hold composite and enum columns as text, the merge casts them back.
+// They are built by hand since sqlc cannot see temp tables.
+const createMediaStaging = `
+CREATE TEMP TABLE media_staging
+(
+    id                 INTEGER,
+    romaji             TEXT,
+    english            TEXT,
+    native             TEXT,
+    type               TEXT,
+    format             TEXT,
+    status             TEXT,
+    season             TEXT,
+    season_year        INTEGER,
+    episodes           INTEGER,
+    chapters           INTEGER,
+    volumes            INTEGER,
+    cover_image        TEXT,
+    genres             TEXT[],
+    average_score      INTEGER,
+    studios            TEXT[],
+    is_adult           BOOLEAN,
+    anilist_updated_at BIGINT,
+    content_hash       TEXT
+) ON COMMIT DROP`
+
+const createMediaDetailsStaging = `
+CREATE TEMP TABLE media_details_staging
+(
+    id                 INTEGER,
+    description        TEXT,
+    start_date         TEXT,
+    end_date           TEXT,
+    duration           INTEGER,
+    country            TEXT,
+    source             TEXT,
+    trailer            TEXT,
+    banner_image       TEXT,
+    popularity         INTEGER,
+    trending           INTEGER,
+    favourites         INTEGER,
+    airing_schedule    TEXT,
+    recommendations    TEXT[],
+    score_distribution TEXT[]
+) ON COMMIT DROP`
+
+const createMediaStudiosStaging = `
+CREATE TEMP TABLE media_studios_staging
+(
+    media_id            INTEGER,
+    studio_id           INTEGER,
+    name                TEXT,
+    is_animation_studio BOOLEAN
+) ON COMMIT DROP`
+
+const createMediaGenresStaging = `
+CREATE TEMP TABLE media_genres_staging
+(
+    media_id INTEGER,
+    name     TEXT
+) ON COMMIT DROP`
+
+const createMediaTagsStaging = `
+CREATE TEMP TABLE media_tags_staging
+(
+    media_id         INTEGER,
+    tag_id           INTEGER,
+    name             TEXT,
+    category         TEXT,
+    rank             INTEGER,
+    is_media_spoiler BOOLEAN,
+    is_adult         BOOLEAN
+) ON COMMIT DROP`
+
+const createMediaRelationsStaging = `
+CREATE TEMP TABLE media_relations_staging
+(
+    media_id      INTEGER,
+    related_id    INTEGER,
+    relation_type TEXT
+) ON COMMIT DROP`
+
+const createAiringEpisodesStaging = `
+CREATE TEMP TABLE airing_episodes_staging
+(
+    media_id  INTEGER,
+    episode   INTEGER,
+    airing_at BIGINT
+) ON COMMIT DROP`
+
+const createMediaStaffStaging = `
+CREATE TEMP TABLE media_staff_staging
+(
+    media_id    INTEGER,
+    staff_id    INTEGER,
+    role        TEXT,
+    position    INTEGER,
+    name_full   TEXT,
+    name_native TEXT,
+    image       TEXT
+) ON COMMIT DROP`
+
+// touchStaging bumps last_checked for every staged media whose content did not change
+const touchStaging = `
+UPDATE media
+SET last_checked = NOW()
+FROM media_staging
+WHERE media.id = media_staging.id
+  AND media.content_hash = media_staging.content_hash`
+
+// recordStagedHistory diffs the tracked fields of changed media before the merge overwrites
+// them, it mirrors RecordMediaHistory in query.sql
+const recordStagedHistory = `
+INSERT INTO media_history (media_id, field, old_value, new_value, sync_run_id)
+SELECT media.id, change.field, change.old_value, change.new_value, $1::BIGINT
+FROM media_staging
+         JOIN media
+              ON media.id = media_staging.id
+         JOIN media_details_staging
+              ON media_details_staging.id = media_staging.id
+         LEFT JOIN media_details
+                   ON media_details.id = media.id
+         CROSS JOIN LATERAL (VALUES ('status', media.status, media_staging.status),
+                                    ('episodes', media.episodes::TEXT, media_staging.episodes::TEXT),
+                                    ('chapters', media.chapters::TEXT, media_staging.chapters::TEXT),
+                                    ('volumes', media.volumes::TEXT, media_staging.volumes::TEXT),
+                                    ('average_score', media.average_score::TEXT, media_staging.average_score::TEXT),
+                                    ('popularity', media_details.popularity::TEXT, media_details_staging.popularity::TEXT),
+                                    ('favourites', media_details.favourites::TEXT, media_details_staging.favourites::TEXT))
+    AS change (field, old_value, new_value)
+WHERE media.content_hash IS DISTINCT FROM media_staging.content_hash
+  AND change.old_value IS DISTINCT FROM change.new_value`
+
+// mergeStaging upserts both tables in one statement, the foreign key from media_details
+// is only checked at the end of the statement so the parent rows are already there.
+// Unchanged media are left out of RETURNING so their details are not rewritten either.
+const mergeStaging = `
+WITH upserted_media AS (
+    INSERT INTO media (id, titles, type, format, status, season, season_year, episodes, chapters, volumes,
+                       cover_image, genres, average_score, studios, is_adult, anilist_updated_at, content_hash,
+                       last_updated, last_checked)
+    SELECT id,
+           ROW (romaji, english, native)::titles,
+           type::media_type,
+           format,
+           status,
+           season,
+           season_year,
+           episodes,
+           chapters,
+           volumes,
+           cover_image,
+           genres,
+           average_score,
+           studios,
+           is_adult,
+           anilist_updated_at,
+           content_hash,
+           NOW(),
+           NOW()
+    FROM media_staging
+    ON CONFLICT (id) DO UPDATE
+    SET titles             = EXCLUDED.titles,
+        type               = EXCLUDED.type,
+        format             = EXCLUDED.format,
+        status             = EXCLUDED.status,
+        season             = EXCLUDED.season,
+        season_year        = EXCLUDED.season_year,
+        episodes           = EXCLUDED.episodes,
+        chapters           = EXCLUDED.chapters,
+        volumes            = EXCLUDED.volumes,
+        cover_image        = EXCLUDED.cover_image,
+        genres             = EXCLUDED.genres,
+        average_score      = EXCLUDED.average_score,
+        studios            = EXCLUDED.studios,
+        is_adult           = EXCLUDED.is_adult,
+        anilist_updated_at = EXCLUDED.anilist_updated_at,
+        content_hash       = EXCLUDED.content_hash,
+        last_updated       = NOW(),
+        last_checked       = NOW()
+    WHERE media.content_hash IS DISTINCT FROM EXCLUDED.content_hash
+    RETURNING id
+)
+INSERT INTO media_details (id, description, start_date, end_date, duration, country, source, trailer, banner_image,
+                           popularity, trending, favourites, airing_schedule, recommendations, score_distribution)
+SELECT staging.id,
+       staging.description,
+       staging.start_date::fuzzy_date,
+       staging.end_date::fuzzy_date,
+       staging.duration,
+       staging.country,
+       staging.source,
+       staging.trailer,
+       staging.banner_image,
+       staging.popularity,
+       staging.trending,
+       staging.favourites,
+       staging.airing_schedule::airing_schedule,
+       staging.recommendations::recommendation[],
+       staging.score_distribution::score_distribution[]
+FROM media_details_staging staging
+JOIN upserted_media USING (id)
+ON CONFLICT (id) DO UPDATE
+SET description        = EXCLUDED.description,
+    start_date         = EXCLUDED.start_date,
+    end_date           = EXCLUDED.end_date,
+    duration           = EXCLUDED.duration,
+    country            = EXCLUDED.country,
+    source             = EXCLUDED.source,
+    trailer            = EXCLUDED.trailer,
+    banner_image       = EXCLUDED.banner_image,
+    popularity         = EXCLUDED.popularity,
+    trending           = EXCLUDED.trending,
+    favourites         = EXCLUDED.favourites,
+    airing_schedule    = EXCLUDED.airing_schedule,
+    recommendations    = EXCLUDED.recommendations,
+    score_distribution = EXCLUDED.score_distribution`
+
+// syncStagedLinks mirrors putMediaLinks for the whole batch, it runs after the merge so the
+// media rows the links reference exist and touches nothing when the links are unchanged
+var syncStagedLinks = []string{`
+INSERT INTO studios (id, name, is_animation_studio)
+SELECT DISTINCT ON (studio_id) studio_id, name, is_animation_studio
+FROM media_studios_staging
+ORDER BY studio_id
+ON CONFLICT (id) DO UPDATE
+SET name                = EXCLUDED.name,
+    is_animation_studio = EXCLUDED.is_animation_studio
+WHERE studios.name IS DISTINCT FROM EXCLUDED.name
+   OR studios.is_animation_studio IS DISTINCT FROM EXCLUDED.is_animation_studio`, `
+DELETE
+FROM media_studios
+    USING media_staging
+WHERE media_studios.media_id = media_staging.id
+  AND NOT EXISTS (SELECT 1
+                  FROM media_studios_staging staging
+                  WHERE staging.media_id = media_studios.media_id
+                    AND staging.studio_id = media_studios.studio_id)`, `
+INSERT INTO media_studios (media_id, studio_id)
+SELECT DISTINCT media_id, studio_id
+FROM media_studios_staging
+ON CONFLICT DO NOTHING`, `
+INSERT INTO genres (name)
+SELECT DISTINCT name
+FROM media_genres_staging
+ON CONFLICT (name) DO NOTHING`, `
+DELETE
+FROM media_genres
+    USING media_staging
+WHERE media_genres.media_id = media_staging.id
+  AND NOT EXISTS (SELECT 1
+                  FROM media_genres_staging staging
+                           JOIN genres
+                                ON genres.name = staging.name
+                  WHERE staging.media_id = media_genres.media_id
+                    AND genres.id = media_genres.genre_id)`, `
+INSERT INTO media_genres (media_id, genre_id)
+SELECT DISTINCT staging.media_id, genres.id
+FROM media_genres_staging staging
+         JOIN genres
+              ON genres.name = staging.name
+ON CONFLICT DO NOTHING`, `
+INSERT INTO tags (id, name, category, is_adult)
+SELECT DISTINCT ON (tag_id) tag_id, name, category, is_adult
+FROM media_tags_staging
+ORDER BY tag_id
+ON CONFLICT (id) DO UPDATE
+SET name     = EXCLUDED.name,
+    category = EXCLUDED.category,
+    is_adult = EXCLUDED.is_adult
+WHERE (tags.name, tags.category, tags.is_adult) IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.category, EXCLUDED.is_adult)`, `
+DELETE
+FROM media_tags
+    USING media_staging
+WHERE media_tags.media_id = media_staging.id
+  AND NOT EXISTS (SELECT 1
+                  FROM media_tags_staging staging
+                  WHERE staging.media_id = media_tags.media_id
+                    AND staging.tag_id = media_tags.tag_id)`, `
+INSERT INTO media_tags (media_id, tag_id, rank, is_media_spoiler)
+SELECT DISTINCT ON (media_id, tag_id) media_id, tag_id, rank, is_media_spoiler
+FROM media_tags_staging
+ORDER BY media_id, tag_id
+ON CONFLICT (media_id, tag_id) DO UPDATE
+SET rank             = EXCLUDED.rank,
+    is_media_spoiler = EXCLUDED.is_media_spoiler
+WHERE (media_tags.rank, media_tags.is_media_spoiler) IS DISTINCT FROM (EXCLUDED.rank, EXCLUDED.is_media_spoiler)`, `
+DELETE
+FROM media_relations
+    USING media_staging
+WHERE media_relations.media_id = media_staging.id
+  AND NOT EXISTS (SELECT 1
+                  FROM media_relations_staging staging
+                  WHERE staging.media_id = media_relations.media_id
+                    AND staging.related_id = media_relations.related_id)`, `
+INSERT INTO media_relations (media_id, related_id, relation_type)
+SELECT DISTINCT ON (media_id, related_id) media_id, related_id, relation_type
+FROM media_relations_staging
+ORDER BY media_id, related_id
+ON CONFLICT (media_id, related_id) DO UPDATE
+SET relation_type = EXCLUDED.relation_type
+WHERE media_relations.relation_type IS DISTINCT FROM EXCLUDED.relation_type`, `
+DELETE
+FROM airing_episodes
+    USING media_staging
+WHERE airing_episodes.media_id = media_staging.id
+  AND airing_episodes.airing_at > EXTRACT(EPOCH FROM NOW())::BIGINT
+  AND NOT EXISTS (SELECT 1
+                  FROM airing_episodes_staging staging
+                  WHERE staging.media_id = airing_episodes.media_id
+                    AND staging.episode = airing_episodes.episode)`, `
+INSERT INTO airing_episodes (media_id, episode, airing_at)
+SELECT DISTINCT ON (media_id, episode) media_id, episode, airing_at
+FROM airing_episodes_staging
+ORDER BY media_id, episode
+ON CONFLICT (media_id, episode) DO UPDATE
+SET airing_at          = EXCLUDED.airing_at,
+    previous_airing_at = airing_episodes.airing_at,
+    rescheduled_at     = NOW(),
+    reschedule_count   = airing_episodes.reschedule_count + 1,
+    updated_at         = NOW()
+WHERE airing_episodes.airing_at IS DISTINCT FROM EXCLUDED.airing_at`, `
+INSERT INTO staff (id, name_full, name_native, image)
+SELECT DISTINCT ON (staff_id) staff_id, NULLIF(name_full, ''), NULLIF(name_native, ''), NULLIF(image, '')
+FROM media_staff_staging
+ORDER BY staff_id
+ON CONFLICT (id) DO UPDATE
+SET name_full   = EXCLUDED.name_full,
+    name_native = EXCLUDED.name_native,
+    image       = COALESCE(EXCLUDED.image, staff.image)
+WHERE (staff.name_full, staff.name_native) IS DISTINCT FROM (EXCLUDED.name_full, EXCLUDED.name_native)
+   OR (EXCLUDED.image IS NOT NULL AND staff.image IS DISTINCT FROM EXCLUDED.image)`, `
+DELETE
+FROM media_staff
+    USING media_staging
+WHERE media_staff.media_id = media_staging.id
+  AND NOT EXISTS (SELECT 1
+                  FROM media_staff_staging staging
+                  WHERE staging.media_id = media_staff.media_id
+                    AND staging.staff_id = media_staff.staff_id
+                    AND staging.role = media_staff.role)`, `
+INSERT INTO media_staff (media_id, staff_id, role, position)
+SELECT DISTINCT ON (media_id, staff_id, role) media_id, staff_id, role, position
+FROM media_staff_staging
+ORDER BY media_id, staff_id, role
+ON CONFLICT (media_id, staff_id, role) DO UPDATE
+SET position = EXCLUDED.position
+WHERE media_staff.position IS DISTINCT FROM EXCLUDED.position`,
+}
+
+const clearStagedFailedMedia = `
+DELETE
+FROM failed_media
+WHERE media_id IN (SELECT id FROM media_staging)`
+
+var mediaStagingColumns = []string{
+	"id", "romaji", "english", "native", "type", "format", "status", "season", "season_year", "episodes",
+	"chapters", "volumes", "cover_image", "genres", "average_score", "studios", "is_adult", "anilist_updated_at",
+	"content_hash",
+}
+
+var mediaDetailsStagingColumns = []string{
+	"id", "description", "start_date", "end_date", "duration", "country", "source", "trailer", "banner_image",
+	"popularity", "trending", "favourites", "airing_schedule", "recommendations", "score_distribution",
+}
+
+var mediaStudiosStagingColumns = []string{"media_id", "studio_id", "name", "is_animation_studio"}
+
+var mediaGenresStagingColumns = []string{"media_id", "name"}
+
+var mediaTagsStagingColumns = []string{"media_id", "tag_id", "name", "category", "rank", "is_media_spoiler", "is_adult"}
+
+var mediaRelationsStagingColumns = []string{"media_id", "related_id", "relation_type"}
+
+var airingEpisodesStagingColumns = []string{"media_id", "episode", "airing_at"}
+
+var mediaStaffStagingColumns = []string{"media_id", "staff_id", "role", "position", "name_full", "name_native", "image"}
+
+// bulkInsertMedia writes a batch of media in a single transaction by copying it into
+// staging tables and merging from there, one bad row fails the whole batch
+func bulkInsertMedia(ctx context.Context, pool *pgxpool.Pool, medias []MediaDetails, runID pgtype.Int8) error {
+	if len(medias) == 0 {
+		return nil
+	}
+
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, createMediaStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMediaDetailsStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMediaStudiosStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMediaGenresStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMediaTagsStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMediaRelationsStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createAiringEpisodesStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMediaStaffStaging); err != nil {
+		return err
+	}
+
+	mediaRows, detailsRows := stagingRows(medias)
+	links := linkStagingRows(medias)
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_staging"}, mediaStagingColumns, pgx.CopyFromRows(mediaRows)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_details_staging"}, mediaDetailsStagingColumns, pgx.CopyFromRows(detailsRows)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_studios_staging"}, mediaStudiosStagingColumns, pgx.CopyFromRows(links.studios)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_genres_staging"}, mediaGenresStagingColumns, pgx.CopyFromRows(links.genres)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_tags_staging"}, mediaTagsStagingColumns, pgx.CopyFromRows(links.tags)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_relations_staging"}, mediaRelationsStagingColumns, pgx.CopyFromRows(links.relations)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"airing_episodes_staging"}, airingEpisodesStagingColumns, pgx.CopyFromRows(links.airing)); err != nil {
+		return err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"media_staff_staging"}, mediaStaffStagingColumns, pgx.CopyFromRows(links.staff)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, touchStaging); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, recordStagedHistory, runID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, mergeStaging); err != nil {
+		return err
+	}
+	for _, stmt := range syncStagedLinks {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, clearStagedFailedMedia); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// stagingRows flattens the batch into copy rows, a media listed twice keeps its last
+// version since ON CONFLICT cannot touch the same row twice in one statement
+func stagingRows(medias []MediaDetails) (mediaRows [][]any, detailsRows [][]any) {
+	latest := make(map[int]int, len(medias))
+	for i, media := range medias {
+		latest[media.ID] = i
+	}
+
+	for i, media := range medias {
+		if latest[media.ID] != i {
+			continue
+		}
+
+		m, d := mediaParams(media)
+		mediaRows = append(mediaRows, []any{
+			m.ID, m.Column2, m.Column3, m.Column4, nullMediaTypeText(m.Type), m.Format, m.Status, m.Season,
+			m.SeasonYear, m.Episodes, m.Chapters, m.Volumes, m.CoverImage, m.Genres, m.AverageScore, m.Studios,
+			m.IsAdult, m.AnilistUpdatedAt, m.ContentHash,
+		})
+		detailsRows = append(detailsRows, []any{
+			d.ID, d.Description, nullStringText(d.StartDate), nullStringText(d.EndDate), d.Duration, d.Country, d.Source, d.Trailer, d.BannerImage,
+			d.Popularity, d.Trending, d.Favourites, nullStringText(d.AiringSchedule), d.Recommendations, d.ScoreDistribution,
+		})
+	}
+
+	return mediaRows, detailsRows
+}
+
+// linkRows holds the copy rows of every link staging table
+type linkRows struct {
+	studios   [][]any
+	genres    [][]any
+	tags      [][]any
+	relations [][]any
+	airing    [][]any
+	staff     [][]any
+}
+
+// linkStagingRows flattens the links of the batch, keeping the same version of a repeated
+// media as stagingRows. Duplicate links are fine since the statements select distinct rows.
+func linkStagingRows(medias []MediaDetails) linkRows {
+	var rows linkRows
+
+	latest := make(map[int]int, len(medias))
+	for i, media := range medias {
+		latest[media.ID] = i
+	}
+
+	for i, media := range medias {
+		if latest[media.ID] != i {
+			continue
+		}
+
+		for _, studio := range media.Studios.Nodes {
+			rows.studios = append(rows.studios, []any{int32(media.ID), int32(studio.ID), studio.Name, studio.IsAnimationStudio})
+		}
+		for _, genre := range media.Genres {
+			rows.genres = append(rows.genres, []any{int32(media.ID), genre})
+		}
+		for _, tag := range media.Tags {
+			rows.tags = append(rows.tags, []any{
+				int32(media.ID), int32(tag.ID), tag.Name, tag.Category, int32(tag.Rank), tag.IsMediaSpoiler, tag.IsAdult,
+			})
+		}
+		for _, relation := range media.Relations.Edges {
+			rows.relations = append(rows.relations, []any{int32(media.ID), int32(relation.Node.ID), relation.RelationType})
+		}
+		for _, episode := range media.AiringSchedule.Nodes {
+			rows.airing = append(rows.airing, []any{int32(media.ID), int32(episode.Episode), int64(episode.AiringAt)})
+		}
+
+		// positions follow the deduped credits like the WITH ORDINALITY of SetMediaStaff
+		staff, credits := staffCredits(int32(media.ID), media.Staff.Edges)
+		members := make(map[int32]int, len(staff.Ids))
+		for i, id := range staff.Ids {
+			members[id] = i
+		}
+		for i, staffID := range credits.StaffIds {
+			member := members[staffID]
+			rows.staff = append(rows.staff, []any{
+				int32(media.ID), staffID, credits.Roles[i], int32(i + 1),
+				staff.NameFulls[member], staff.NameNatives[member], staff.Images[member],
+			})
+		}
+	}
+
+	return rows
+}
+
+// the copy protocol encodes by column type, hand it plain strings rather than database/sql wrappers
+func nullStringText(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullMediaTypeText(t database.NullMediaType) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := string(t.MediaType)
+	return &s
+}
@@ -0,0 +1,101 @@
+package media
+
+import (
+	"context"
+	"media-worker/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CharacterMediaCap is how many media a characters run visits, least recently synced first
+var CharacterMediaCap = 500
+
+// voiceActorLanguage matches the language MediaCharacters asks voiceActors for
+const voiceActorLanguage = "JAPANESE"
+
+const charactersCredit = "characters"
+
+// UpdateCharacters pages through the characters and Japanese voice actors of the media whose
+// characters were synced longest ago, sharing the rate limiter and retry policy with the media updaters
+func UpdateCharacters(ctx context.Context, url string) (Result, error) {
+	return updateCredits(ctx, url, charactersCredit, CharacterMediaCap, syncCharacters)
+}
+
+func syncCharacters(ctx context.Context, pool *pgxpool.Pool, q *database.Queries, handler *GraphqlHandler, mediaID int32) (int, error) {
+	edges, err := fetchConnection(ctx, handler, MediaCharacters, mediaID, 1, func(response MediaCharactersResponse) ([]CharacterEdge, bool) {
+		return response.Media.Characters.Edges, response.Media.Characters.PageInfo.HasNextPage
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(edges), insertCharacters(ctx, pool, q, mediaID, edges)
+}
+
+// insertCharacters replaces the characters and voice actors of a media in one transaction
+// and marks them synced
+func insertCharacters(ctx context.Context, pool *pgxpool.Pool, q *database.Queries, mediaID int32, edges []CharacterEdge) error {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	qtx := q.WithTx(tx)
+	defer tx.Rollback(ctx)
+
+	characters := database.UpsertCharactersParams{Ids: []int32{}, NameFulls: []string{}, NameNatives: []string{}, Images: []string{}}
+	links := database.SetMediaCharactersParams{MediaID: mediaID, CharacterIds: []int32{}, Roles: []string{}}
+	voiceActors := database.SetCharacterVoiceActorsParams{
+		MediaID:      mediaID,
+		Language:     voiceActorLanguage,
+		CharacterIds: []int32{},
+		StaffIds:     []int32{},
+	}
+	var staff []Staff
+
+	// a character can show up on two pages if AniList reorders mid-walk, keep its first position
+	seen := make(map[int]bool, len(edges))
+	for _, edge := range edges {
+		if seen[edge.Node.ID] {
+			continue
+		}
+		seen[edge.Node.ID] = true
+
+		characterID := int32(edge.Node.ID)
+		characters.Ids = append(characters.Ids, characterID)
+		characters.NameFulls = append(characters.NameFulls, edge.Node.Name.Full)
+		characters.NameNatives = append(characters.NameNatives, edge.Node.Name.Native)
+		characters.Images = append(characters.Images, edge.Node.Image.Large)
+
+		links.CharacterIds = append(links.CharacterIds, characterID)
+		links.Roles = append(links.Roles, edge.Role)
+
+		for _, actor := range edge.VoiceActors {
+			voiceActors.CharacterIds = append(voiceActors.CharacterIds, characterID)
+			voiceActors.StaffIds = append(voiceActors.StaffIds, int32(actor.ID))
+			staff = append(staff, actor)
+		}
+	}
+
+	if err := qtx.UpsertCharacters(ctx, characters); err != nil {
+		return err
+	}
+	if err := qtx.UpsertStaff(ctx, staffParams(staff)); err != nil {
+		return err
+	}
+	if err := qtx.SetMediaCharacters(ctx, links); err != nil {
+		return err
+	}
+	if err := qtx.SetCharacterVoiceActors(ctx, voiceActors); err != nil {
+		return err
+	}
+	if err := qtx.MarkCreditSynced(ctx, database.MarkCreditSyncedParams{
+		MediaID: mediaID,
+		Credit:  charactersCredit,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
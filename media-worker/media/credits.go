@@ -0,0 +1,91 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// creditSyncer fetches and stores one kind of credit for a media, returning how many entries it stored
+type creditSyncer func(ctx context.Context, pool *pgxpool.Pool, q *database.Queries, handler *GraphqlHandler, mediaID int32) (int, error)
+
+// updateCredits runs sync over the mediaCap media whose credit was synced longest ago
+func updateCredits(ctx context.Context, url string, credit string, mediaCap int, sync creditSyncer) (Result, error) {
+	pool, err := NewPool(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer pool.Close()
+	q := database.New(pool)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+
+	ids, err := q.ListMediaForCreditSync(ctx, database.ListMediaForCreditSyncParams{
+		Credit: credit,
+		Limit:  int32(mediaCap),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	synced := 0
+	var result Result
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			fmt.Printf("Stopping before media %d: %s\n", id, ctx.Err())
+			break
+		}
+
+		stored, err := sync(ctx, pool, q, handler, id)
+		if err != nil {
+			fmt.Printf("%s of media %d failed with error: %s\n", credit, id, err)
+			result.FailedIds = append(result.FailedIds, int(id))
+			continue
+		}
+
+		synced++
+		fmt.Printf("Stored %d %s of media %d\n", stored, credit, id)
+	}
+
+	fmt.Printf("Synced %s of %d media\n", credit, synced)
+	fmt.Printf("Failed media ids: %v\n", result.FailedIds)
+
+	return result, ctx.Err()
+}
+
+// fetchConnection follows a paginated connection of a single media from firstPage until its last page,
+// edges pulls one page of edges out of a response along with whether another page follows
+func fetchConnection[R any, E any](
+	ctx context.Context,
+	handler *GraphqlHandler,
+	query string,
+	mediaID int32,
+	firstPage int,
+	edges func(R) ([]E, bool),
+) ([]E, error) {
+	var all []E
+
+	for page := firstPage; ; page++ {
+		var response R
+		err := PageRetryPolicy.Do(ctx, func(attempt int) error {
+			var zero R
+			response = zero
+			variables := map[string]interface{}{
+				"id":   mediaID,
+				"page": page,
+			}
+			_, err := handler.Query(ctx, query, variables, &response)
+			return err
+		}, IsRetryable)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+
+		pageEdges, hasNextPage := edges(response)
+		all = append(all, pageEdges...)
+		if !hasNextPage {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type ErrorKind int
+
+const (
+	KindNetwork ErrorKind = iota
+	KindRateLimited
+	KindServer
+	KindValidation
+	KindNotFound
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNetwork:
+		return "network"
+	case KindRateLimited:
+		return "rate limited"
+	case KindServer:
+		return "server"
+	case KindValidation:
+		return "validation"
+	case KindNotFound:
+		return "not found"
+	default:
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+}
+
+// QueryError is returned by GraphqlHandler.Query for every failure that reached (or tried to reach) AniList
+type QueryError struct {
+	Kind          ErrorKind
+	HTTPStatus    int
+	GraphQLStatus int
+	RetryAfter    time.Duration
+	Err           error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s error (http %d, graphql %d): %s", e.Kind, e.HTTPStatus, e.GraphQLStatus, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether sending the same request again could succeed
+func (e *QueryError) Retryable() bool {
+	switch e.Kind {
+	case KindNetwork, KindRateLimited, KindServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether err is a QueryError of a transient kind
+func IsRetryable(err error) bool {
+	var queryErr *QueryError
+	return errors.As(err, &queryErr) && queryErr.Retryable()
+}
+
+// graphqlErrorBody is the part of an AniList error response the client library throws away
+type graphqlErrorBody struct {
+	Errors []struct {
+		Message string `json:"message"`
+		Status  int    `json:"status"`
+	} `json:"errors"`
+}
+
+func classifyError(err error, capture *responseCapture) *QueryError {
+	queryErr := &QueryError{
+		HTTPStatus: capture.status,
+		Err:        err,
+	}
+
+	// nothing came back, the connection itself failed or timed out
+	if capture.status == 0 {
+		queryErr.Kind = KindNetwork
+		return queryErr
+	}
+
+	var body graphqlErrorBody
+	if json.Unmarshal(capture.body, &body) == nil && len(body.Errors) != 0 {
+		queryErr.GraphQLStatus = body.Errors[0].Status
+	}
+
+	status := queryErr.GraphQLStatus
+	if status == 0 {
+		status = capture.status
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		queryErr.Kind = KindRateLimited
+		queryErr.RetryAfter = retryAfter(capture.headers)
+	case status == http.StatusNotFound:
+		queryErr.Kind = KindNotFound
+	case status >= 500:
+		queryErr.Kind = KindServer
+	case status >= 400:
+		queryErr.Kind = KindValidation
+	default:
+		// a 200 that failed to decode, most likely a truncated body
+		queryErr.Kind = KindServer
+	}
+
+	return queryErr
+}
@@ -1,31 +1,65 @@
 package media
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/machinebox/graphql"
 )
 
+// RequestTimeout bounds a single AniList request, a zero value disables the deadline
+var RequestTimeout = 30 * time.Second
+
+// responseCapture holds the response metadata for exactly one request, it travels
+// in the request context so concurrent queries never see each other's headers
+type responseCapture struct {
+	headers http.Header
+	status  int
+	body    []byte
+}
+
+type responseCaptureKey struct{}
+
 type headerCapturingTransport struct {
 	underlyingTransport http.RoundTripper
-	headers             http.Header
 }
 
 func (h *headerCapturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	resp, err := h.underlyingTransport.RoundTrip(req)
-	if err == nil {
-		h.headers = resp.Header
+	if err != nil {
+		return resp, err
+	}
+
+	capture, ok := req.Context().Value(responseCaptureKey{}).(*responseCapture)
+	if !ok {
+		return resp, nil
+	}
+
+	// keep a copy of the body, the graphql client drops the error status when it decodes it
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
 	}
-	return resp, err
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	capture.headers = resp.Header
+	capture.status = resp.StatusCode
+	capture.body = body
+	return resp, nil
 }
 
+// GraphqlHandler is safe for concurrent use, share one per run so connections get reused
 type GraphqlHandler struct {
-	client    *graphql.Client
-	transport *headerCapturingTransport
+	client  *graphql.Client
+	timeout time.Duration
+	limiter *RateLimiter
 }
 
-func NewGraphQLHandler(url string) *GraphqlHandler {
+func NewGraphQLHandler(url string, timeout time.Duration, limiter *RateLimiter) *GraphqlHandler {
 	capturingTransport := &headerCapturingTransport{
 		underlyingTransport: http.DefaultTransport,
 	}
@@ -34,21 +68,47 @@ func NewGraphQLHandler(url string) *GraphqlHandler {
 	graphqlClient := graphql.NewClient(url, graphql.WithHTTPClient(httpClient))
 
 	return &GraphqlHandler{
-		client:    graphqlClient,
-		transport: capturingTransport,
+		client:  graphqlClient,
+		timeout: timeout,
+		limiter: limiter,
 	}
 }
 
-func (handler *GraphqlHandler) Query(query string, variables map[string]interface{}, graphqlResponse interface{}) (headers http.Header, err error) {
+func (handler *GraphqlHandler) Query(ctx context.Context, query string, variables map[string]interface{}, graphqlResponse interface{}) (headers http.Header, err error) {
 	graphqlRequest := graphql.NewRequest(query)
 
 	for key, value := range variables {
 		graphqlRequest.Var(key, value)
 	}
 
-	if err := handler.client.Run(context.Background(), graphqlRequest, graphqlResponse); err != nil {
-		return handler.transport.headers, err
+	// waiting on the limiter must not eat into the request deadline
+	if handler.limiter != nil {
+		if err := handler.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	requestCtx := ctx
+	if handler.timeout > 0 {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, handler.timeout)
+		defer cancel()
+	}
+
+	capture := &responseCapture{headers: http.Header{}}
+	requestCtx = context.WithValue(requestCtx, responseCaptureKey{}, capture)
+
+	err = handler.client.Run(requestCtx, graphqlRequest, graphqlResponse)
+	if handler.limiter != nil {
+		handler.limiter.Observe(capture.status, capture.headers)
+	}
+	if err != nil {
+		// the caller gave up, that is not something AniList did
+		if ctx.Err() != nil {
+			return capture.headers, ctx.Err()
+		}
+		return capture.headers, classifyError(err, capture)
 	}
 
-	return handler.transport.headers, nil
+	return capture.headers, nil
 }
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-worker/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// IncrementalPageCap stops an incremental sync after this many pages even if it never catches up
+var IncrementalPageCap = 200
+
+const incrementalMode = "incremental"
+
+// UpdateChangedMedia walks DiscoverUpdatedMedia most recently updated first, writing only media whose
+// AniList updatedAt is newer than ours. It stops once a page reaches below the high-water mark of the
+// last completed run, refreshes also store anilist_updated_at so the stored values cannot tell it where to stop.
+func UpdateChangedMedia(ctx context.Context, url string) (Result, error) {
+	pool, err := NewPool(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer pool.Close()
+	q := database.New(pool)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+
+	mark, err := q.GetHighWaterMark(ctx, incrementalMode)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return Result{}, err
+	}
+	if mark.Valid {
+		fmt.Printf("Walking changes down to updatedAt %d\n", mark.Int64)
+	} else {
+		fmt.Printf("No completed incremental run yet, walking up to %d pages\n", IncrementalPageCap)
+	}
+
+	run, err := startSyncRun(ctx, q, incrementalMode, false)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var newest int64
+	result, summary := walkPages(ctx, pool, q, handler, DiscoverUpdatedMedia, IncrementalPageCap, run.historyID(),
+		func(page int, medias []MediaDetails) (pageDecision, error) {
+			changed, err := changedMedia(ctx, q, medias)
+			if err != nil {
+				return pageDecision{}, err
+			}
+
+			reachedMark := false
+			for _, media := range medias {
+				newest = max(newest, media.UpdatedAt)
+				if mark.Valid && media.UpdatedAt < mark.Int64 {
+					reachedMark = true
+				}
+			}
+			return pageDecision{write: changed, stop: reachedMark}, nil
+		})
+
+	if err := run.finish(ctx, summary.lastPage, result.FailedPages, result.FailedIds); err != nil {
+		return result, err
+	}
+
+	switch summary.end {
+	case walkStopped:
+		fmt.Printf("Caught up on page %d after writing %d media\n", summary.lastPage, summary.written)
+	case walkExhausted:
+		fmt.Printf("Walked every page, wrote %d media\n", summary.written)
+	case walkCapped:
+		fmt.Printf("Reached the cap of %d pages before catching up, wrote %d media\n", IncrementalPageCap, summary.written)
+	}
+
+	// the mark only moves once everything above the old one was walked, otherwise the next run
+	// would stop above the changes this one missed. The first run has no gap to protect.
+	caughtUp := summary.end == walkStopped || summary.end == walkExhausted
+	if newest > 0 && len(result.FailedPages) == 0 && ctx.Err() == nil && (caughtUp || !mark.Valid) {
+		if err := q.SetSyncRunHighWaterMark(ctx, database.SetSyncRunHighWaterMarkParams{
+			ID:            run.id,
+			HighWaterMark: pgtype.Int8{Int64: newest, Valid: true},
+		}); err != nil {
+			return result, err
+		}
+		fmt.Printf("High-water mark is now updatedAt %d\n", newest)
+	} else if mark.Valid {
+		fmt.Printf("Keeping the high-water mark at updatedAt %d\n", mark.Int64)
+	}
+
+	return result, ctx.Err()
+}
+
+// changedMedia drops the media whose stored updatedAt is at least as new as AniList's, it only
+// decides what gets written since a refresh may already have stored the newest version
+func changedMedia(ctx context.Context, q *database.Queries, medias []MediaDetails) ([]MediaDetails, error) {
+	ids := make([]int32, len(medias))
+	for i, media := range medias {
+		ids[i] = int32(media.ID)
+	}
+
+	rows, err := q.GetMediaUpdatedAt(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		if row.AnilistUpdatedAt.Valid {
+			stored[int(row.ID)] = row.AnilistUpdatedAt.Int64
+		}
+	}
+
+	var changed []MediaDetails
+	for _, media := range medias {
+		if updatedAt, ok := stored[media.ID]; ok && updatedAt >= media.UpdatedAt {
+			continue
+		}
+		changed = append(changed, media)
+	}
+	return changed, nil
+}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"context"
+	"media-worker/database"
+)
+
+// putMediaLinks syncs the join tables, airing episodes and staff credits of a media with what AniList returned. It runs even when
+// the content hash is unchanged so rows stored before the tables existed still get linked,
+// the queries only write when a link, name or air time actually differs.
+func putMediaLinks(ctx context.Context, q *database.Queries, media MediaDetails) error {
+	mediaID := int32(media.ID)
+
+	genres := media.Genres
+	if genres == nil {
+		genres = []string{}
+	}
+
+	if err := q.UpsertGenres(ctx, genres); err != nil {
+		return err
+	}
+	if err := q.SetMediaGenres(ctx, database.SetMediaGenresParams{
+		MediaID: mediaID,
+		Names:   genres,
+	}); err != nil {
+		return err
+	}
+
+	if err := q.SetMediaStudios(ctx, studioParams(mediaID, media.Studios.Nodes)); err != nil {
+		return err
+	}
+
+	if err := q.SetMediaTags(ctx, tagParams(mediaID, media.Tags)); err != nil {
+		return err
+	}
+
+	if err := q.SetMediaRelations(ctx, relationParams(mediaID, media.Relations.Edges)); err != nil {
+		return err
+	}
+
+	if err := q.SetAiringEpisodes(ctx, airingParams(mediaID, media.AiringSchedule.Nodes)); err != nil {
+		return err
+	}
+
+	staff, credits := staffCredits(mediaID, media.Staff.Edges)
+	if err := q.UpsertStaff(ctx, staff); err != nil {
+		return err
+	}
+	if err := q.SetMediaStaff(ctx, credits); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// studioParams splits studios into the parallel arrays SetMediaStudios unnests. The arrays are
+// never nil since "<> ALL (NULL)" would keep stale links, and a studio listed twice is dropped
+// because the upsert cannot touch the same row twice in one statement.
+func studioParams(mediaID int32, studios []Studio) database.SetMediaStudiosParams {
+	params := database.SetMediaStudiosParams{
+		StudioIds:          []int32{},
+		Names:              []string{},
+		IsAnimationStudios: []bool{},
+		MediaID:            mediaID,
+	}
+
+	seen := make(map[int]bool, len(studios))
+	for _, studio := range studios {
+		if seen[studio.ID] {
+			continue
+		}
+		seen[studio.ID] = true
+
+		params.StudioIds = append(params.StudioIds, int32(studio.ID))
+		params.Names = append(params.Names, studio.Name)
+		params.IsAnimationStudios = append(params.IsAnimationStudios, studio.IsAnimationStudio)
+	}
+
+	return params
+}
+
+// tagParams splits tags into the parallel arrays SetMediaTags unnests, following studioParams
+func tagParams(mediaID int32, tags []Tag) database.SetMediaTagsParams {
+	params := database.SetMediaTagsParams{
+		TagIds:          []int32{},
+		Names:           []string{},
+		Categories:      []string{},
+		IsAdults:        []bool{},
+		MediaID:         mediaID,
+		Ranks:           []int32{},
+		IsMediaSpoilers: []bool{},
+	}
+
+	seen := make(map[int]bool, len(tags))
+	for _, tag := range tags {
+		if seen[tag.ID] {
+			continue
+		}
+		seen[tag.ID] = true
+
+		params.TagIds = append(params.TagIds, int32(tag.ID))
+		params.Names = append(params.Names, tag.Name)
+		params.Categories = append(params.Categories, tag.Category)
+		params.IsAdults = append(params.IsAdults, tag.IsAdult)
+		params.Ranks = append(params.Ranks, int32(tag.Rank))
+		params.IsMediaSpoilers = append(params.IsMediaSpoilers, tag.IsMediaSpoiler)
+	}
+
+	return params
+}
+
+// relationParams splits relation edges into the parallel arrays SetMediaRelations unnests,
+// the first edge to a media wins if AniList lists it twice
+func relationParams(mediaID int32, relations []Relation) database.SetMediaRelationsParams {
+	params := database.SetMediaRelationsParams{
+		MediaID:       mediaID,
+		RelatedIds:    []int32{},
+		RelationTypes: []string{},
+	}
+
+	seen := make(map[int]bool, len(relations))
+	for _, relation := range relations {
+		if seen[relation.Node.ID] {
+			continue
+		}
+		seen[relation.Node.ID] = true
+
+		params.RelatedIds = append(params.RelatedIds, int32(relation.Node.ID))
+		params.RelationTypes = append(params.RelationTypes, relation.RelationType)
+	}
+
+	return params
+}
+
+// airingParams splits the upcoming episodes into the parallel arrays SetAiringEpisodes unnests
+func airingParams(mediaID int32, schedule []AiringEpisode) database.SetAiringEpisodesParams {
+	params := database.SetAiringEpisodesParams{
+		MediaID:   mediaID,
+		Episodes:  []int32{},
+		AiringAts: []int64{},
+	}
+
+	seen := make(map[int]bool, len(schedule))
+	for _, episode := range schedule {
+		if seen[episode.Episode] {
+			continue
+		}
+		seen[episode.Episode] = true
+
+		params.Episodes = append(params.Episodes, int32(episode.Episode))
+		params.AiringAts = append(params.AiringAts, int64(episode.AiringAt))
+	}
+
+	return params
+}
@@ -46,16 +46,55 @@ const mediaFields = `
 	favourites
 	studios(isMain: true){
 		nodes {
+			id
 			name
+			isAnimationStudio
 		}
 	}
+	tags {
+		id
+		name
+		category
+		rank
+		isMediaSpoiler
+		isAdult
+	}
 	isAdult
-	airingSchedule(notYetAired: true) {
+	airingSchedule(notYetAired: true, perPage: 50) {
+		pageInfo {
+			hasNextPage
+		}
 		nodes {
 			airingAt
 			episode
 		}
 	}
+	staff(sort: [RELEVANCE, ID], perPage: 25) {
+		pageInfo {
+			hasNextPage
+		}
+		edges {
+			role
+			node {
+				id
+				name {
+					full
+					native
+				}
+				image {
+					large
+				}
+			}
+		}
+	}
+	relations {
+		edges {
+			relationType
+			node {
+				id
+			}
+		}
+	}
 	recommendations(sort: RATING_DESC perPage: 5) {
 		nodes {
 			rating
@@ -70,6 +109,7 @@ const mediaFields = `
 			amount
 		}   
 	}
+	updatedAt
 `
 
 var DiscoverMedia = fmt.Sprintf(`
@@ -113,3 +153,116 @@ var DiscoverNewMedia = fmt.Sprintf(`
 		}
 	}
 `, mediaFields)
+
+var DiscoverUpdatedMedia = fmt.Sprintf(`
+	query DiscoverUpdatedMedia($page: Int) {
+		Page(page: $page, perPage: 50) {
+			pageInfo {
+				currentPage
+				hasNextPage
+			}
+			media(sort: UPDATED_AT_DESC) {
+				%s
+			}
+		}
+	}
+`, mediaFields)
+
+// MediaAiringSchedule continues the upcoming episodes of a media past the page mediaFields fetched
+const MediaAiringSchedule = `
+	query MediaAiringSchedule($id: Int, $page: Int) {
+		Media(id: $id) {
+			airingSchedule(notYetAired: true, page: $page, perPage: 50) {
+				pageInfo {
+					currentPage
+					hasNextPage
+				}
+				nodes {
+					airingAt
+					episode
+				}
+			}
+		}
+	}
+`
+
+// RecentlyAiredEpisodes lists every episode that aired between $from and $to across all media,
+// the per media airingSchedule can only skip aired episodes, not window them
+const RecentlyAiredEpisodes = `
+	query RecentlyAiredEpisodes($page: Int, $from: Int, $to: Int) {
+		Page(page: $page, perPage: 50) {
+			pageInfo {
+				currentPage
+				hasNextPage
+			}
+			airingSchedules(airingAt_greater: $from, airingAt_lesser: $to, sort: TIME) {
+				mediaId
+				episode
+				airingAt
+			}
+		}
+	}
+`
+
+const MediaCharacters = `
+	query MediaCharacters($id: Int, $page: Int) {
+		Media(id: $id) {
+			characters(sort: [ROLE, ID], page: $page, perPage: 25) {
+				pageInfo {
+					currentPage
+					hasNextPage
+				}
+				edges {
+					role
+					node {
+						id
+						name {
+							full
+							native
+						}
+						image {
+							large
+						}
+					}
+					voiceActors(language: JAPANESE) {
+						id
+						name {
+							full
+							native
+						}
+						image {
+							large
+						}
+					}
+				}
+			}
+		}
+	}
+`
+
+// MediaStaff continues the staff credits of a media past the page mediaFields fetched
+const MediaStaff = `
+	query MediaStaff($id: Int, $page: Int) {
+		Media(id: $id) {
+			staff(sort: [RELEVANCE, ID], page: $page, perPage: 25) {
+				pageInfo {
+					currentPage
+					hasNextPage
+				}
+				edges {
+					role
+					node {
+						id
+						name {
+							full
+							native
+						}
+						image {
+							large
+						}
+					}
+				}
+			}
+		}
+	}
+`
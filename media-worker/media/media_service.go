@@ -2,12 +2,16 @@ package media
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math"
 	"media-worker/database"
 	"os"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -17,103 +21,132 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func UpdatePage(url string, query string, pages []int) {
-	ctx := context.Background()
-	connStr := fmt.Sprintf(
-		"host=%s user=%s password=%s database=%s port=5432",
-		os.Getenv("PG_HOST"),
-		os.Getenv("PG_USER"),
-		os.Getenv("PG_PASSWORD"),
-		os.Getenv("PG_DATABASE"),
-	)
+// UpdateMedia re-fetches the media in idList through query as a sync run of mode, pool is kept open
+// by the caller across runs
+func UpdateMedia(ctx context.Context, pool *pgxpool.Pool, url string, mode string, query string, idList []int32) (Result, error) {
+	run, err := startSyncRun(ctx, database.New(pool), mode, false)
+	if err != nil {
+		return Result{}, err
+	}
 
-	poolCfg, _ := pgxpool.ParseConfig(connStr)
-	poolCfg.MaxConns = 10
-	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+	return updateMedia(ctx, pool, handler, query, idList, run), ctx.Err()
+}
+
+// BackfillMedia walks every page of DiscoverMedia, checkpointing into sync_runs after each page.
+// With resume set it continues the latest unfinished backfill instead of starting from page 1.
+func BackfillMedia(ctx context.Context, url string, resume bool) (Result, error) {
+	pool, err := NewPool(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return Result{}, err
 	}
-	q := database.New(pool)
+	defer pool.Close()
 
-	for _, page := range pages {
-		success := false
+	run, err := startSyncRun(ctx, database.New(pool), "all", resume)
+	if err != nil {
+		return Result{}, err
+	}
 
-		for attempt := 1; attempt <= 5; attempt++ {
-			fmt.Printf("Starting page: %d with attempt: %d\n", page, attempt)
-			response, timeout, err := discoverMedia(url, query, page, nil)
-			if err != nil {
-				fmt.Printf("Page %d failed with error: %s\n", page, err)
-				continue
-			}
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+	return updateMedia(ctx, pool, handler, DiscoverMedia, nil, run), ctx.Err()
+}
 
-			if timeout != 0 {
-				fmt.Printf("rate‑limit timeout (%d s); sleeping...", timeout)
-				time.Sleep(time.Duration(timeout) * time.Second)
-				continue
-			}
+// insertPage writes a page one media at a time, stopping at the first failure
+func insertPage(ctx context.Context, pool *pgxpool.Pool, q *database.Queries, medias []MediaDetails, runID pgtype.Int8) error {
+	for _, media := range medias {
+		fmt.Printf("Starting media with ID: %d\n", media.ID)
+		if err := insertMedia(ctx, pool, q, media, runID); err != nil {
+			fmt.Printf("Media with id %d failed with error: %s\n", media.ID, err)
+			return err
+		}
+	}
+	return nil
+}
 
-			mediaFailed := false
+// RetryFailedMedia re-fetches every media in failed_media, entries clear themselves once inserted
+func RetryFailedMedia(ctx context.Context, url string) (Result, error) {
+	pool, err := NewPool(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer pool.Close()
 
-			for _, media := range response.Page.Media {
-				fmt.Printf("Starting media with ID: %d\n", media.ID)
-				if err := insertMedia(ctx, pool, q, media); err != nil {
-					fmt.Printf("Media with id %d failed with error: %s\n", media.ID, err)
-					mediaFailed = true
-					break
-				}
-			}
+	q := database.New(pool)
+	ids, err := q.ListFailedMediaIds(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(ids) == 0 {
+		fmt.Println("No failed media to retry")
+		return Result{}, nil
+	}
 
-			if mediaFailed {
-				fmt.Println("Media failed.")
-				continue
-			}
-			success = true
-			break
-		}
+	run, err := startSyncRun(ctx, q, "retry-failed", false)
+	if err != nil {
+		return Result{}, err
+	}
 
-		if !success {
-			fmt.Printf("Page %d failed\n", page)
-		} else {
-			fmt.Printf("Page %d succeeded\n", page)
-		}
+	fmt.Printf("Retrying %d failed media\n", len(ids))
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+	result := updateMedia(ctx, pool, handler, UpdateFromMediaList, ids, run)
+
+	remaining, err := q.ListFailedMediaIds(context.WithoutCancel(ctx))
+	if err != nil {
+		return result, err
 	}
+	fmt.Printf("Cleared %d of %d failed media\n", len(ids)-len(remaining), len(ids))
+
+	return result, ctx.Err()
 }
 
-func UpdateMedia(url string, query string, idList []int32) {
-	const (
-		rateLimitPerMin = 30
-		windowSeconds   = 65.0
-	)
+func updateMedia(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	handler *GraphqlHandler,
+	query string,
+	idList []int32,
+	run *syncRun,
+) Result {
 	var failedPages []int
 	var failedIds []int
+	var failedMu sync.Mutex
+
+	startPage := 1
+	if run != nil {
+		startPage = run.lastCompletedPage + 1
+		failedPages = run.failedPages
+		failedIds = run.failedIds
+	}
 
 	stop := false
-	jobs := make(chan MediaDetails)
+	tracker := newPageTracker(startPage - 1)
+	jobs := make(chan pageJob)
 	failedJobs := make(chan MediaDetails)
 	done := make(chan bool, 1)
 
 	// loading the async failed ids onto failedIds
 	go func(done chan bool) {
 		for failed := range failedJobs {
+			failedMu.Lock()
 			failedIds = append(failedIds, failed.ID)
+			failedMu.Unlock()
 		}
 		done <- true
 	}(done)
 
-	ctx := context.Background()
-	connStr := fmt.Sprintf(
-		"host=%s user=%s password=%s database=%s port=5432",
-		os.Getenv("PG_HOST"),
-		os.Getenv("PG_USER"),
-		os.Getenv("PG_PASSWORD"),
-		os.Getenv("PG_DATABASE"),
-	)
+	// saveCheckpoint is best effort, losing one only means redoing a few pages on resume
+	saveCheckpoint := func() {
+		if run == nil {
+			return
+		}
 
-	poolCfg, _ := pgxpool.ParseConfig(connStr)
-	poolCfg.MaxConns = 10
-	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
-	if err != nil {
-		log.Fatal(err)
+		failedMu.Lock()
+		ids := slices.Clone(failedIds)
+		failedMu.Unlock()
+
+		if err := run.checkpoint(ctx, tracker.lastCompleted(), failedPages, ids); err != nil && ctx.Err() == nil {
+			log.Printf("checkpoint for sync run %d failed: %v", run.id, err)
+		}
 	}
 
 	var wg sync.WaitGroup
@@ -124,47 +157,28 @@ func UpdateMedia(url string, query string, idList []int32) {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			dbWorker(id, jobs, failedJobs, pool, q)
+			dbWorker(ctx, id, jobs, failedJobs, pool, q, tracker, run.historyID())
 		}(i)
 	}
 
-	windowStart := time.Now()
-	idx := 0
+	for page := startPage; stop == false; {
+		if ctx.Err() != nil {
+			fmt.Printf("Stopping before page %d: %s\n", page, ctx.Err())
+			break
+		}
 
-	for page := 1; stop == false; {
-		fmt.Printf("Starting page: %d\n", page)
-		response, timeout, err := discoverMedia(url, query, page, idList)
+		response, err := fetchPage(ctx, handler, query, page, idList)
 		if err != nil {
-			fmt.Printf("Page %d failed with error: %s\n", page, err)
 			failedPages = append(failedPages, page)
+			tracker.add(page, 0)
+			saveCheckpoint()
 			page++
 			continue
 		}
 
-		// the rate limits reset after timeout, need to start new 30 cycle
-		if timeout != 0 {
-			fmt.Printf("rate‑limit timeout (%d s); sleeping...", timeout)
-			time.Sleep(time.Duration(timeout) * time.Second)
-			idx, windowStart = 0, time.Now()
-			continue
-		}
-
 		// Split the individual media to worker
-		for _, media := range response.Page.Media {
-			jobs <- media
-		}
-
-		fraction := float64(idx+1) / float64(rateLimitPerMin)
-		targetElapsed := time.Duration(
-			windowSeconds * math.Pow(fraction, 1.3) * float64(time.Second),
-		)
-		sleepFor := targetElapsed - time.Since(windowStart)
-		time.Sleep(sleepFor)
-
-		idx++
-		if idx == rateLimitPerMin {
-			idx, windowStart = 0, time.Now()
-		}
+		dispatch(ctx, jobs, tracker, page, response.Page.Media)
+		saveCheckpoint()
 
 		if response.Page.PageInfo.HasNextPage == false {
 			stop = true
@@ -173,11 +187,33 @@ func UpdateMedia(url string, query string, idList []int32) {
 		page++
 	}
 
+	// one last pass over the pages that ran out of attempts, AniList hiccups rarely last a whole run
+	if len(failedPages) != 0 && ctx.Err() == nil {
+		fmt.Printf("Retrying %d failed pages\n", len(failedPages))
+
+		var stillFailed []int
+		for _, page := range failedPages {
+			response, err := fetchPage(ctx, handler, query, page, idList)
+			if err != nil {
+				stillFailed = append(stillFailed, page)
+				continue
+			}
+			dispatch(ctx, jobs, tracker, page, response.Page.Media)
+		}
+		failedPages = stillFailed
+	}
+
 	close(jobs)
 	wg.Wait()
 	close(failedJobs)
 	<-done
 
+	if run != nil {
+		if err := run.finish(ctx, tracker.lastCompleted(), failedPages, failedIds); err != nil {
+			log.Printf("finishing sync run %d failed: %v", run.id, err)
+		}
+	}
+
 	for _, page := range failedPages {
 		fmt.Printf("Page %d failed\n", page)
 	}
@@ -188,26 +224,102 @@ func UpdateMedia(url string, query string, idList []int32) {
 
 	fmt.Printf("All pages queried with %d failed pages and %d failed inserts\n", len(failedPages), len(failedIds))
 	fmt.Printf("Took %s\n", time.Since(start))
+
+	return Result{FailedPages: failedPages, FailedIds: failedIds}
+}
+
+// fetchPage requests a single page under PageRetryPolicy
+func fetchPage(ctx context.Context, handler *GraphqlHandler, query string, page int, idList []int32) (MediaQueryResponse, error) {
+	var response MediaQueryResponse
+
+	err := PageRetryPolicy.Do(ctx, func(attempt int) error {
+		fmt.Printf("Starting page: %d with attempt: %d\n", page, attempt)
+		var err error
+		response, err = discoverMedia(ctx, handler, query, page, idList)
+		if err != nil {
+			fmt.Printf("Page %d failed with error: %s\n", page, err)
+		}
+		return err
+	}, IsRetryable)
+
+	return response, err
+}
+
+type pageJob struct {
+	page   int
+	medias []MediaDetails
+}
+
+// dispatch hands the media of a page to the db workers
+func dispatch(ctx context.Context, jobs chan<- pageJob, tracker *pageTracker, page int, medias []MediaDetails) {
+	if len(medias) == 0 {
+		return
+	}
+
+	tracker.add(page, len(medias))
+
+	select {
+	case jobs <- pageJob{page: page, medias: medias}:
+	case <-ctx.Done():
+		// the page stays pending so it never counts as complete
+	}
 }
 
 func dbWorker(
+	ctx context.Context,
 	id int,
-	jobs <-chan MediaDetails,
+	jobs <-chan pageJob,
 	failedJobs chan MediaDetails,
 	pool *pgxpool.Pool,
 	q *database.Queries,
+	tracker *pageTracker,
+	runID pgtype.Int8,
 ) {
 	for job := range jobs {
-		fmt.Printf("Worker %d processing media with id: %d\n", id, job.ID)
-		if err := insertMedia(context.Background(), pool, q, job); err != nil {
-			log.Printf("worker %d: %v", id, err)
-			failedJobs <- job
+		fmt.Printf("Worker %d processing page %d with %d media\n", id, job.page, len(job.medias))
+		err := bulkInsertMedia(ctx, pool, job.medias, runID)
+		if err == nil {
+			tracker.done(job.page, len(job.medias))
+			continue
+		}
+		// cancelled mid-insert, leave the page pending so a resume redoes it
+		if ctx.Err() != nil {
+			continue
+		}
+
+		// fall back to a transaction per media so one bad row does not sink the whole page
+		log.Printf("worker %d: bulk insert of page %d failed, inserting one by one: %v", id, job.page, err)
+		for _, media := range job.medias {
+			if err := insertMedia(ctx, pool, q, media, runID); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				log.Printf("worker %d: %v", id, err)
+				if err := recordFailedMedia(ctx, q, media, err); err != nil {
+					log.Printf("worker %d: recording failed media %d: %v", id, media.ID, err)
+				}
+				failedJobs <- media
+			}
+			tracker.done(job.page, 1)
 		}
 	}
 }
 
-func discoverMedia(url string, query string, page int, idList []int32) (response MediaQueryResponse, timeout int, err error) {
-	handler := NewGraphQLHandler(url)
+// recordFailedMedia dead-letters a media together with the payload that failed to insert
+func recordFailedMedia(ctx context.Context, q *database.Queries, media MediaDetails, insertErr error) error {
+	payload, err := json.Marshal(media)
+	if err != nil {
+		return err
+	}
+
+	return q.RecordFailedMedia(ctx, database.RecordFailedMediaParams{
+		MediaID: int32(media.ID),
+		Error:   insertErr.Error(),
+		Payload: payload,
+	})
+}
+
+func discoverMedia(ctx context.Context, handler *GraphqlHandler, query string, page int, idList []int32) (response MediaQueryResponse, err error) {
 	variables := map[string]interface{}{
 		"page": page,
 	}
@@ -218,15 +330,56 @@ func discoverMedia(url string, query string, page int, idList []int32) (response
 
 	var graphqlResponse MediaQueryResponse
 
-	if headers, err := handler.Query(query, variables, &graphqlResponse); err != nil {
-		if timeout := headers.Get("Retry-After"); timeout != "" {
-			timeoutSeconds, _ := strconv.Atoi(timeout)
-			return MediaQueryResponse{}, timeoutSeconds, nil
+	if _, err := handler.Query(ctx, query, variables, &graphqlResponse); err != nil {
+		return MediaQueryResponse{}, err
+	}
+	if err := completeMedia(ctx, handler, graphqlResponse.Page.Media); err != nil {
+		return MediaQueryResponse{}, err
+	}
+
+	return graphqlResponse, nil
+}
+
+// completeMedia fetches the rest of the nested connections that did not fit on the first page,
+// only the rare media with more than a page of them cost extra requests
+func completeMedia(ctx context.Context, handler *GraphqlHandler, medias []MediaDetails) error {
+	for i := range medias {
+		media := &medias[i]
+
+		if media.AiringSchedule.PageInfo.HasNextPage {
+			rest, err := fetchConnection(ctx, handler, MediaAiringSchedule, int32(media.ID), 2,
+				func(response MediaAiringScheduleResponse) ([]AiringEpisode, bool) {
+					schedule := response.Media.AiringSchedule
+					return schedule.Nodes, schedule.PageInfo.HasNextPage
+				})
+			if err != nil {
+				return fmt.Errorf("airing schedule of media %d: %w", media.ID, err)
+			}
+			media.AiringSchedule.Nodes = append(media.AiringSchedule.Nodes, rest...)
+		}
+
+		if media.Staff.PageInfo.HasNextPage {
+			rest, err := fetchConnection(ctx, handler, MediaStaff, int32(media.ID), 2,
+				func(response MediaStaffResponse) ([]StaffEdge, bool) {
+					staff := response.Media.Staff
+					return staff.Edges, staff.PageInfo.HasNextPage
+				})
+			if err != nil {
+				return fmt.Errorf("staff of media %d: %w", media.ID, err)
+			}
+			media.Staff.Edges = append(media.Staff.Edges, rest...)
 		}
-		return MediaQueryResponse{}, 0, err
 	}
+	return nil
+}
 
-	return graphqlResponse, 0, nil
+// isRetryablePageError also retries database errors, only AniList can tell us a request is hopeless
+func isRetryablePageError(err error) bool {
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		return queryErr.Retryable()
+	}
+	return true
 }
 
 func insertMedia(
@@ -234,6 +387,7 @@ func insertMedia(
 	pool *pgxpool.Pool,
 	q *database.Queries,
 	media MediaDetails,
+	runID pgtype.Int8,
 ) error {
 	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -243,12 +397,56 @@ func insertMedia(
 	qtx := q.WithTx(tx)
 	defer tx.Rollback(ctx)
 
+	mediaRow, detailsRow := mediaParams(media)
+
+	// identical data only bumps last_checked, last_updated keeps meaning the content changed
+	checked, err := qtx.MarkMediaChecked(ctx, database.MarkMediaCheckedParams{
+		ID:          mediaRow.ID,
+		ContentHash: mediaRow.ContentHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	if checked == 0 {
+		// diff against the stored row before it is overwritten
+		if err := qtx.RecordMediaHistory(ctx, historyParams(mediaRow, detailsRow, runID)); err != nil {
+			return err
+		}
+
+		if err := qtx.PutMedia(ctx, mediaRow); err != nil {
+			return err
+		}
+
+		if err := qtx.PutMediaDetails(ctx, detailsRow); err != nil {
+			return err
+		}
+	}
+
+	if err := putMediaLinks(ctx, qtx, media); err != nil {
+		return err
+	}
+
+	// a successful write means the media is no longer dead-lettered
+	if err := qtx.ClearFailedMedia(ctx, int32(media.ID)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// mediaParams converts an AniList media into the rows of media and media_details
+func mediaParams(media MediaDetails) (database.PutMediaParams, database.PutMediaDetailsParams) {
 	toText := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: s != ""} }
 	toInt4 := func(n int) pgtype.Int4 { return pgtype.Int4{Int32: int32(n), Valid: n != 0} }
 	toBool := func(b bool) pgtype.Bool { return pgtype.Bool{Bool: b, Valid: true} }
 	toDate := func(fuzzyDate FuzzyDate) sql.NullString {
 		return sql.NullString{String: fmt.Sprintf("(%d, %d, %d)",
-			media.StartDate.Year, media.StartDate.Month, media.StartDate.Day),
+			fuzzyDate.Year, fuzzyDate.Month, fuzzyDate.Day),
 			Valid: fuzzyDate != (FuzzyDate{})}
 	}
 	toNullMediaType := func(s string) database.NullMediaType {
@@ -260,26 +458,25 @@ func insertMedia(
 		studios = append(studios, v.Name)
 	}
 
-	if err := qtx.PutMedia(ctx, database.PutMediaParams{
-		ID:           int32(media.ID),
-		Column2:      media.Titles.Romaji,
-		Column3:      media.Titles.English,
-		Column4:      media.Titles.Native,
-		Type:         toNullMediaType(media.Type),
-		Format:       toText(media.Format),
-		Status:       media.Status,
-		Season:       toText(media.Season),
-		SeasonYear:   toInt4(media.SeasonYear),
-		Episodes:     toInt4(media.Episodes),
-		Chapters:     toInt4(media.Chapters),
-		Volumes:      toInt4(media.Volumes),
-		CoverImage:   toText(media.CoverImage.Large),
-		Genres:       media.Genres,
-		AverageScore: toInt4(media.AverageScore),
-		Studios:      studios,
-		IsAdult:      toBool(media.IsAdult),
-	}); err != nil {
-		return err
+	mediaRow := database.PutMediaParams{
+		ID:               int32(media.ID),
+		Column2:          media.Titles.Romaji,
+		Column3:          media.Titles.English,
+		Column4:          media.Titles.Native,
+		Type:             toNullMediaType(media.Type),
+		Format:           toText(media.Format),
+		Status:           media.Status,
+		Season:           toText(media.Season),
+		SeasonYear:       toInt4(media.SeasonYear),
+		Episodes:         toInt4(media.Episodes),
+		Chapters:         toInt4(media.Chapters),
+		Volumes:          toInt4(media.Volumes),
+		CoverImage:       toText(media.CoverImage.Large),
+		Genres:           media.Genres,
+		AverageScore:     toInt4(media.AverageScore),
+		Studios:          studios,
+		IsAdult:          toBool(media.IsAdult),
+		AnilistUpdatedAt: pgtype.Int8{Int64: media.UpdatedAt, Valid: media.UpdatedAt != 0},
 	}
 
 	var recommendations []string
@@ -300,7 +497,7 @@ func insertMedia(
 		scores = append(scores, scoreString)
 	}
 
-	if err := qtx.PutMediaDetails(ctx, database.PutMediaDetailsParams{
+	detailsRow := database.PutMediaDetailsParams{
 		ID:          int32(media.ID),
 		Description: pgtype.Text{String: media.Description, Valid: true},
 		StartDate:   toDate(media.StartDate),
@@ -317,13 +514,81 @@ func insertMedia(
 		AiringSchedule:    airingSch,
 		Recommendations:   recommendations,
 		ScoreDistribution: scores,
-	}); err != nil {
-		return err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return err
+	mediaRow.ContentHash = pgtype.Text{String: contentHash(mediaRow, detailsRow), Valid: true}
+
+	return mediaRow, detailsRow
+}
+
+// historyParams carries the new values of the fields media_history tracks
+func historyParams(
+	mediaRow database.PutMediaParams,
+	detailsRow database.PutMediaDetailsParams,
+	runID pgtype.Int8,
+) database.RecordMediaHistoryParams {
+	int4Text := func(n pgtype.Int4) pgtype.Text {
+		return pgtype.Text{String: strconv.Itoa(int(n.Int32)), Valid: n.Valid}
+	}
+	int32Text := func(n int32) pgtype.Text {
+		return pgtype.Text{String: strconv.Itoa(int(n)), Valid: true}
 	}
 
-	return nil
+	return database.RecordMediaHistoryParams{
+		SyncRunID:    runID,
+		Status:       mediaRow.Status,
+		Episodes:     int4Text(mediaRow.Episodes),
+		Chapters:     int4Text(mediaRow.Chapters),
+		Volumes:      int4Text(mediaRow.Volumes),
+		AverageScore: int4Text(mediaRow.AverageScore),
+		Popularity:   int32Text(detailsRow.Popularity),
+		Favourites:   int32Text(detailsRow.Favourites),
+		ID:           mediaRow.ID,
+	}
+}
+
+// contentHash fingerprints everything we store for a media, the hash itself must still be unset
+func contentHash(mediaRow database.PutMediaParams, detailsRow database.PutMediaDetailsParams) string {
+	payload, _ := json.Marshal(struct {
+		Media   database.PutMediaParams
+		Details database.PutMediaDetailsParams
+	}{mediaRow, detailsRow})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// sleepCtx sleeps for d or until ctx is cancelled, whichever comes first
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// NewPool opens a pool on the PG_* environment, every updater opens its own unless given one
+func NewPool(ctx context.Context) (*pgxpool.Pool, error) {
+	connStr := fmt.Sprintf(
+		"host=%s user=%s password=%s database=%s port=5432",
+		os.Getenv("PG_HOST"),
+		os.Getenv("PG_USER"),
+		os.Getenv("PG_PASSWORD"),
+		os.Getenv("PG_DATABASE"),
+	)
+
+	poolCfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	poolCfg.MaxConns = 10
+	return pgxpool.NewWithConfig(ctx, poolCfg)
 }
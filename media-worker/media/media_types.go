@@ -40,23 +40,22 @@ type MediaDetails struct {
 	Trending     int      `json:"trending"`
 	Favourites   int      `json:"favourites"`
 	Studios      struct {
-		Nodes []struct {
-			Name string `json:"name"`
-		} `json:"nodes"`
+		Nodes []Studio `json:"nodes"`
 	} `json:"studios"`
-	IsAdult        bool `json:"isAdult"`
-	AiringSchedule struct {
-		Nodes []struct {
-			AiringAt int `json:"airingAt"`
-			Episode  int `json:"episode"`
-		} `json:"nodes"`
-	} `json:"airingSchedule"`
+	Tags           []Tag                    `json:"tags"`
+	IsAdult        bool                     `json:"isAdult"`
+	AiringSchedule AiringScheduleConnection `json:"airingSchedule"`
+	Staff          StaffConnection          `json:"staff"`
+	Relations      struct {
+		Edges []Relation `json:"edges"`
+	} `json:"relations"`
 	Recommendations struct {
 		Nodes []Recommendation `json:"nodes"`
 	} `json:"recommendations"`
 	Stats struct {
 		ScoreDistribution []Score `json:"scoreDistribution"`
 	} `json:"stats"`
+	UpdatedAt int64 `json:"updatedAt"`
 }
 
 type Titles struct {
@@ -76,6 +75,57 @@ type Trailer struct {
 	Site string `json:"site"`
 }
 
+type Studio struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	IsAnimationStudio bool   `json:"isAnimationStudio"`
+}
+
+type Tag struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Category       string `json:"category"`
+	Rank           int    `json:"rank"`
+	IsMediaSpoiler bool   `json:"isMediaSpoiler"`
+	IsAdult        bool   `json:"isAdult"`
+}
+
+type AiringEpisode struct {
+	AiringAt int `json:"airingAt"`
+	Episode  int `json:"episode"`
+}
+
+type AiringScheduleConnection struct {
+	PageInfo PageInfo        `json:"pageInfo"`
+	Nodes    []AiringEpisode `json:"nodes"`
+}
+
+type MediaAiringScheduleResponse struct {
+	Media struct {
+		AiringSchedule AiringScheduleConnection `json:"airingSchedule"`
+	} `json:"Media"`
+}
+
+type RecentlyAiredResponse struct {
+	Page struct {
+		PageInfo        PageInfo       `json:"pageInfo"`
+		AiringSchedules []AiredEpisode `json:"airingSchedules"`
+	} `json:"Page"`
+}
+
+type AiredEpisode struct {
+	MediaID  int `json:"mediaId"`
+	Episode  int `json:"episode"`
+	AiringAt int `json:"airingAt"`
+}
+
+type Relation struct {
+	RelationType string `json:"relationType"`
+	Node         struct {
+		ID int `json:"id"`
+	} `json:"node"`
+}
+
 type Recommendation struct {
 	Rating              int `json:"rating"`
 	MediaRecommendation struct {
@@ -87,3 +137,55 @@ type Score struct {
 	Score  int `json:"score"`
 	Amount int `json:"amount"`
 }
+
+type MediaCharactersResponse struct {
+	Media struct {
+		Characters struct {
+			PageInfo PageInfo        `json:"pageInfo"`
+			Edges    []CharacterEdge `json:"edges"`
+		} `json:"characters"`
+	} `json:"Media"`
+}
+
+type CharacterEdge struct {
+	Role        string    `json:"role"`
+	Node        Character `json:"node"`
+	VoiceActors []Staff   `json:"voiceActors"`
+}
+
+type StaffConnection struct {
+	PageInfo PageInfo    `json:"pageInfo"`
+	Edges    []StaffEdge `json:"edges"`
+}
+
+type MediaStaffResponse struct {
+	Media struct {
+		Staff StaffConnection `json:"staff"`
+	} `json:"Media"`
+}
+
+type StaffEdge struct {
+	Role string `json:"role"`
+	Node Staff  `json:"node"`
+}
+
+type Character struct {
+	ID    int        `json:"id"`
+	Name  PersonName `json:"name"`
+	Image struct {
+		Large string `json:"large"`
+	} `json:"image"`
+}
+
+type Staff struct {
+	ID    int        `json:"id"`
+	Name  PersonName `json:"name"`
+	Image struct {
+		Large string `json:"large"`
+	} `json:"image"`
+}
+
+type PersonName struct {
+	Full   string `json:"full"`
+	Native string `json:"native"`
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewMediaPageCap stops new media discovery after this many pages even if it never reaches known media
+var NewMediaPageCap = 40
+
+// UpdateNewMedia pages through DiscoverNewMedia newest first and stops at the first page
+// made up entirely of media we already have, or once NewMediaPageCap is reached
+func UpdateNewMedia(ctx context.Context, url string) (Result, error) {
+	pool, err := NewPool(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer pool.Close()
+
+	return UpdateNewMediaWithPool(ctx, pool, url)
+}
+
+// UpdateNewMediaWithPool is UpdateNewMedia on a pool the caller keeps open across runs
+func UpdateNewMediaWithPool(ctx context.Context, pool *pgxpool.Pool, url string) (Result, error) {
+	q := database.New(pool)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+
+	run, err := startSyncRun(ctx, q, "new", false)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, summary := walkPages(ctx, pool, q, handler, DiscoverNewMedia, NewMediaPageCap, run.historyID(),
+		func(page int, medias []MediaDetails) (pageDecision, error) {
+			allKnown, err := allMediaKnown(ctx, q, medias)
+			return pageDecision{write: medias, stop: allKnown}, err
+		})
+
+	if err := run.finish(ctx, summary.lastPage, result.FailedPages, result.FailedIds); err != nil {
+		return result, err
+	}
+
+	switch summary.end {
+	case walkStopped:
+		fmt.Printf("Page %d only has media already in the database, stopping\n", summary.lastPage)
+	case walkCapped:
+		fmt.Printf("Reached the cap of %d pages before finding known media\n", NewMediaPageCap)
+	}
+	return result, ctx.Err()
+}
+
+func allMediaKnown(ctx context.Context, q *database.Queries, medias []MediaDetails) (bool, error) {
+	if len(medias) == 0 {
+		return false, nil
+	}
+
+	ids := make([]int32, len(medias))
+	for i, media := range medias {
+		ids[i] = int32(media.ID)
+	}
+
+	known, err := q.FilterKnownMediaIds(ctx, ids)
+	if err != nil {
+		return false, err
+	}
+	return len(known) == len(ids), nil
+}
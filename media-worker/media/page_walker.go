@@ -0,0 +1,104 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pageDecision is what a walk does with one page, write is stored and stop ends the walk after it
+type pageDecision struct {
+	write []MediaDetails
+	stop  bool
+}
+
+// walkEnd is why a walk finished
+type walkEnd int
+
+const (
+	// walkStopped means decide asked to stop
+	walkStopped walkEnd = iota
+	// walkExhausted means AniList had no further page
+	walkExhausted
+	// walkCapped means pageCap pages were walked first
+	walkCapped
+	// walkCancelled means ctx was cancelled
+	walkCancelled
+)
+
+type walkSummary struct {
+	end      walkEnd
+	lastPage int
+	written  int
+}
+
+// walkPages fetches query page by page up to pageCap and writes what decide picks from each page.
+// decide only sees the first response of a page, a retry would otherwise see its own partial inserts.
+func walkPages(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	q *database.Queries,
+	handler *GraphqlHandler,
+	query string,
+	pageCap int,
+	runID pgtype.Int8,
+	decide func(page int, medias []MediaDetails) (pageDecision, error),
+) (Result, walkSummary) {
+	var result Result
+	summary := walkSummary{end: walkCapped}
+
+	for page := 1; page <= pageCap; page++ {
+		if ctx.Err() != nil {
+			fmt.Printf("Stopping before page %d: %s\n", page, ctx.Err())
+			summary.end = walkCancelled
+			return result, summary
+		}
+		summary.lastPage = page
+
+		var decision pageDecision
+		decided := false
+		hasNextPage := true
+
+		err := PageRetryPolicy.Do(ctx, func(attempt int) error {
+			fmt.Printf("Starting page: %d with attempt: %d\n", page, attempt)
+			response, err := discoverMedia(ctx, handler, query, page, nil)
+			if err != nil {
+				fmt.Printf("Page %d failed with error: %s\n", page, err)
+				return err
+			}
+
+			if !decided {
+				decision, err = decide(page, response.Page.Media)
+				if err != nil {
+					return err
+				}
+				decided = true
+			}
+			hasNextPage = response.Page.PageInfo.HasNextPage
+
+			return insertPage(ctx, pool, q, decision.write, runID)
+		}, isRetryablePageError)
+
+		if err != nil {
+			fmt.Printf("Page %d failed\n", page)
+			result.FailedPages = append(result.FailedPages, page)
+			continue
+		}
+		summary.written += len(decision.write)
+		fmt.Printf("Page %d succeeded with %d written media\n", page, len(decision.write))
+
+		if decision.stop {
+			summary.end = walkStopped
+			return result, summary
+		}
+		if !hasNextPage {
+			summary.end = walkExhausted
+			return result, summary
+		}
+	}
+
+	return result, summary
+}
@@ -0,0 +1,126 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AniList is in degraded mode at 30 req/min, the headers will raise this if the limit comes back up
+const (
+	defaultRateLimit = 30
+	rateLimitWindow  = time.Minute
+)
+
+// sharedLimiter is used by every updater in the process so they draw from one AniList budget
+var sharedLimiter = NewRateLimiter(defaultRateLimit, rateLimitWindow)
+
+// RateLimiter is a token bucket that adapts to the X-RateLimit-* headers AniList sends back
+type RateLimiter struct {
+	mu           sync.Mutex
+	limit        int
+	window       time.Duration
+	tokens       float64
+	lastRefill   time.Time
+	blockedUntil time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:      limit,
+		window:     window,
+		tokens:     float64(limit),
+		lastRefill: time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is cancelled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.refill(now)
+
+		var wait time.Duration
+		switch {
+		case now.Before(l.blockedUntil):
+			wait = l.blockedUntil.Sub(now)
+		case l.tokens >= 1:
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		default:
+			wait = time.Duration((1 - l.tokens) / l.rate() * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Observe adjusts the bucket from the status and rate limit headers of a response
+func (l *RateLimiter) Observe(status int, headers http.Header) {
+	if headers == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	if limit, err := strconv.Atoi(headers.Get("X-RateLimit-Limit")); err == nil && limit > 0 && limit != l.limit {
+		fmt.Printf("AniList rate limit changed from %d to %d per %s\n", l.limit, limit, l.window)
+		l.limit = limit
+		l.tokens = math.Min(l.tokens, float64(limit))
+	}
+
+	// the server's count is authoritative, only ever lower ours since responses can arrive out of order
+	remaining, err := strconv.Atoi(headers.Get("X-RateLimit-Remaining"))
+	if err == nil && float64(remaining) < l.tokens {
+		l.tokens = float64(remaining)
+	}
+	exhausted := (err == nil && remaining <= 0) || status == http.StatusTooManyRequests
+
+	// the reset time only matters once the budget is spent, before that the bucket paces us
+	if reset, err := strconv.ParseInt(headers.Get("X-RateLimit-Reset"), 10, 64); err == nil && exhausted {
+		l.blockUntil(time.Unix(reset, 0))
+	}
+
+	if wait := retryAfter(headers); wait > 0 {
+		l.blockUntil(now.Add(wait))
+		l.tokens = 0
+	}
+}
+
+// retryAfter reads the Retry-After header, AniList always sends it in seconds
+func retryAfter(headers http.Header) time.Duration {
+	seconds, err := strconv.Atoi(headers.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (l *RateLimiter) blockUntil(until time.Time) {
+	if until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+func (l *RateLimiter) rate() float64 {
+	return float64(l.limit) / l.window.Seconds()
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.lastRefill).Seconds()
+	l.tokens = math.Min(float64(l.limit), l.tokens+elapsed*l.rate())
+	l.lastRefill = now
+}
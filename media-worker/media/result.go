@@ -0,0 +1,11 @@
+package media
+
+// Result is what a run could not finish, callers treat a non-empty result as a partial failure
+type Result struct {
+	FailedPages []int
+	FailedIds   []int
+}
+
+func (r Result) Failed() bool {
+	return len(r.FailedPages) != 0 || len(r.FailedIds) != 0
+}
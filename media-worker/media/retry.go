@@ -0,0 +1,92 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how a failed page is re-requested
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is the fraction of the backoff that is randomised, 0.2 means ±20%
+	Jitter float64
+	// MaxElapsed stops retrying once this much time has passed since the first attempt, zero means no limit
+	MaxElapsed time.Duration
+	// MaxRateLimited caps the rate limited retries, they do not count towards MaxAttempts
+	MaxRateLimited int
+}
+
+// PageRetryPolicy is shared by every AniList request the updaters make
+var PageRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+	MaxElapsed:     5 * time.Minute,
+	MaxRateLimited: 10,
+}
+
+// Backoff returns how long to wait after the given failed attempt, attempts start at 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// Do runs op until it succeeds, returns an error retryable rejects, or the policy gives up.
+// Rate limited attempts wait for Retry-After and have their own cap instead of counting as attempts.
+func (p RetryPolicy) Do(ctx context.Context, op func(attempt int) error, retryable func(error) bool) error {
+	start := time.Now()
+	rateLimited := 0
+
+	for attempt := 1; ; {
+		err := op(attempt)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if p.MaxElapsed > 0 && time.Since(start) >= p.MaxElapsed {
+			return fmt.Errorf("giving up after %s: %w", time.Since(start).Round(time.Second), err)
+		}
+
+		var queryErr *QueryError
+		if errors.As(err, &queryErr) && queryErr.Kind == KindRateLimited {
+			rateLimited++
+			if rateLimited > p.MaxRateLimited {
+				return fmt.Errorf("giving up after %d rate limited attempts: %w", rateLimited, err)
+			}
+			if err := sleepCtx(ctx, max(queryErr.RetryAfter, p.Backoff(attempt))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !retryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		if err := sleepCtx(ctx, p.Backoff(attempt)); err != nil {
+			return err
+		}
+		attempt++
+	}
+}
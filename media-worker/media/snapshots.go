@@ -0,0 +1,32 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+)
+
+// Snapshots stay daily for SnapshotDailyDays, then keep one per week until SnapshotWeeklyDays, then one per month
+var (
+	SnapshotDailyDays  = 90
+	SnapshotWeeklyDays = 365
+)
+
+// RecordSnapshots stores today's score and popularity numbers for ids and downsamples old snapshots
+func RecordSnapshots(ctx context.Context, q *database.Queries, ids []int32) error {
+	written, err := q.SnapshotMedia(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	pruned, err := q.PruneMediaSnapshots(ctx, database.PruneMediaSnapshotsParams{
+		WeeklyDays: int32(SnapshotWeeklyDays),
+		DailyDays:  int32(SnapshotDailyDays),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshotted %d media and pruned %d old snapshots\n", written, pruned)
+	return nil
+}
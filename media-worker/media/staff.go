@@ -0,0 +1,57 @@
+package media
+
+import (
+	"media-worker/database"
+	"slices"
+)
+
+// staffCredits dedupes the staff edges of a media into the rows UpsertStaff and SetMediaStaff
+// unnest, a staff member keeps one credit per role in the order AniList ranked them
+func staffCredits(mediaID int32, edges []StaffEdge) (database.UpsertStaffParams, database.SetMediaStaffParams) {
+	staff := make([]Staff, 0, len(edges))
+	credits := database.SetMediaStaffParams{MediaID: mediaID, StaffIds: []int32{}, Roles: []string{}}
+
+	type credit struct {
+		staffID int
+		role    string
+	}
+	seen := make(map[credit]bool, len(edges))
+	for _, edge := range edges {
+		key := credit{edge.Node.ID, edge.Role}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		staff = append(staff, edge.Node)
+		credits.StaffIds = append(credits.StaffIds, int32(edge.Node.ID))
+		credits.Roles = append(credits.Roles, edge.Role)
+	}
+
+	return staffParams(staff), credits
+}
+
+// staffParams splits staff into the parallel arrays UpsertStaff unnests, repeats are dropped
+// since a voice actor plays several characters and a staff member can hold several roles.
+// Rows go in id order so concurrent writers sharing staff lock them in the same order.
+func staffParams(staff []Staff) database.UpsertStaffParams {
+	params := database.UpsertStaffParams{Ids: []int32{}, NameFulls: []string{}, NameNatives: []string{}, Images: []string{}}
+
+	sorted := slices.Clone(staff)
+	slices.SortStableFunc(sorted, func(a, b Staff) int { return a.ID - b.ID })
+
+	seen := make(map[int]bool, len(sorted))
+	for _, member := range sorted {
+		if seen[member.ID] {
+			continue
+		}
+		seen[member.ID] = true
+
+		params.Ids = append(params.Ids, int32(member.ID))
+		params.NameFulls = append(params.NameFulls, member.Name.Full)
+		params.NameNatives = append(params.NameNatives, member.Name.Native)
+		params.Images = append(params.Images, member.Image.Large)
+	}
+
+	return params
+}
@@ -0,0 +1,139 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"media-worker/database"
+)
+
+// syncChunkSize matches the perPage of UpdateFromMediaList so every chunk is a single request
+const syncChunkSize = 50
+
+type SyncOutcome int
+
+const (
+	SyncStored SyncOutcome = iota
+	// SyncMissing means AniList did not return the id, it was deleted or never existed
+	SyncMissing
+	SyncFailed
+	// SyncSkipped means the run was cancelled before the id's chunk was requested
+	SyncSkipped
+)
+
+func (o SyncOutcome) String() string {
+	switch o {
+	case SyncStored:
+		return "stored"
+	case SyncMissing:
+		return "missing"
+	case SyncFailed:
+		return "failed"
+	case SyncSkipped:
+		return "not attempted"
+	default:
+		return fmt.Sprintf("SyncOutcome(%d)", int(o))
+	}
+}
+
+// SyncStatus is what happened to a single requested id, Err is set when it failed
+type SyncStatus struct {
+	ID      int32
+	Outcome SyncOutcome
+	Err     error
+}
+
+// SyncIDs fetches the given AniList ids through UpdateFromMediaList in chunks of 50 and upserts them
+// one by one, returning a status per distinct id in the order they were asked for. Ids left over
+// when ctx is cancelled are reported as SyncSkipped.
+func SyncIDs(ctx context.Context, url string, ids []int32) ([]SyncStatus, error) {
+	pool, err := NewPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+	q := database.New(pool)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+
+	run, err := startSyncRun(ctx, q, "fetch", false)
+	if err != nil {
+		return nil, err
+	}
+
+	ids = uniqueIds(ids)
+	statuses := make([]SyncStatus, 0, len(ids))
+
+	// a chunk is one page of the run
+	var failedChunks []int
+	chunks := 0
+	for start := 0; start < len(ids); start += syncChunkSize {
+		chunk := ids[start:min(start+syncChunkSize, len(ids))]
+		if ctx.Err() != nil {
+			fmt.Printf("Stopping before media %d: %s\n", chunk[0], ctx.Err())
+			for _, id := range ids[start:] {
+				statuses = append(statuses, SyncStatus{ID: id, Outcome: SyncSkipped})
+			}
+			break
+		}
+		chunks++
+
+		response, err := fetchPage(ctx, handler, UpdateFromMediaList, 1, chunk)
+		if err != nil {
+			failedChunks = append(failedChunks, chunks)
+			for _, id := range chunk {
+				statuses = append(statuses, SyncStatus{ID: id, Outcome: SyncFailed, Err: err})
+			}
+			continue
+		}
+
+		fetched := make(map[int32]MediaDetails, len(response.Page.Media))
+		for _, media := range response.Page.Media {
+			fetched[int32(media.ID)] = media
+		}
+
+		for _, id := range chunk {
+			media, ok := fetched[id]
+			if !ok {
+				statuses = append(statuses, SyncStatus{ID: id, Outcome: SyncMissing})
+				continue
+			}
+
+			if err := insertMedia(ctx, pool, q, media, run.historyID()); err != nil {
+				if ctx.Err() == nil {
+					if err := recordFailedMedia(ctx, q, media, err); err != nil {
+						log.Printf("recording failed media %d: %v", id, err)
+					}
+				}
+				statuses = append(statuses, SyncStatus{ID: id, Outcome: SyncFailed, Err: err})
+				continue
+			}
+			statuses = append(statuses, SyncStatus{ID: id, Outcome: SyncStored})
+		}
+	}
+
+	var failedIds []int
+	for _, status := range statuses {
+		if status.Outcome == SyncFailed {
+			failedIds = append(failedIds, int(status.ID))
+		}
+	}
+	if err := run.finish(ctx, chunks, failedChunks, failedIds); err != nil {
+		return statuses, err
+	}
+
+	return statuses, ctx.Err()
+}
+
+// uniqueIds drops repeated ids while keeping the order they were given in
+func uniqueIds(ids []int32) []int32 {
+	seen := make(map[int32]bool, len(ids))
+	unique := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -0,0 +1,156 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-worker/database"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// the statuses finish writes, runs are created and reopened as running by the queries themselves
+const (
+	syncStatusCompleted = "completed"
+	syncStatusCancelled = "cancelled"
+)
+
+// syncRun persists the progress of a long run in sync_runs so it can be resumed
+type syncRun struct {
+	id                int64
+	q                 *database.Queries
+	lastCompletedPage int
+	failedPages       []int
+	failedIds         []int
+}
+
+// startSyncRun picks up the latest unfinished run of mode when resume is set, otherwise starts a new one
+func startSyncRun(ctx context.Context, q *database.Queries, mode string, resume bool) (*syncRun, error) {
+	if resume {
+		row, err := q.GetResumableSyncRun(ctx, mode)
+		switch {
+		case err == nil:
+			if err := q.ReopenSyncRun(ctx, row.ID); err != nil {
+				return nil, err
+			}
+			fmt.Printf("Resuming sync run %d after page %d\n", row.ID, row.LastCompletedPage)
+			return &syncRun{
+				id:                row.ID,
+				q:                 q,
+				lastCompletedPage: int(row.LastCompletedPage),
+				failedPages:       toInts(row.FailedPages),
+				failedIds:         toInts(row.FailedIds),
+			}, nil
+		case errors.Is(err, pgx.ErrNoRows):
+			fmt.Printf("No %s run to resume, starting from the first page\n", mode)
+		default:
+			return nil, err
+		}
+	}
+
+	row, err := q.CreateSyncRun(ctx, mode)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Started sync run %d\n", row.ID)
+
+	return &syncRun{id: row.ID, q: q}, nil
+}
+
+func (r *syncRun) checkpoint(ctx context.Context, lastCompletedPage int, failedPages []int, failedIds []int) error {
+	return r.q.CheckpointSyncRun(ctx, database.CheckpointSyncRunParams{
+		ID:                r.id,
+		LastCompletedPage: int32(lastCompletedPage),
+		FailedPages:       toInt32s(failedPages),
+		FailedIds:         toInt32s(failedIds),
+	})
+}
+
+// finish records the final state, it uses a fresh context since ctx is usually the reason we stopped
+func (r *syncRun) finish(ctx context.Context, lastCompletedPage int, failedPages []int, failedIds []int) error {
+	status := syncStatusCompleted
+	if ctx.Err() != nil {
+		status = syncStatusCancelled
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if err := r.checkpoint(ctx, lastCompletedPage, failedPages, failedIds); err != nil {
+		return err
+	}
+	return r.q.FinishSyncRun(ctx, database.FinishSyncRunParams{ID: r.id, Status: status})
+}
+
+// historyID is the run media_history rows are attributed to, none outside a sync run
+func (r *syncRun) historyID() pgtype.Int8 {
+	if r == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: r.id, Valid: true}
+}
+
+// pageTracker works out the highest page whose media have all been written, the db workers
+// finish out of order so the last dispatched page is not necessarily done
+type pageTracker struct {
+	mu         sync.Mutex
+	pending    map[int]int
+	dispatched int
+}
+
+func newPageTracker(startAfter int) *pageTracker {
+	return &pageTracker{pending: map[int]int{}, dispatched: startAfter}
+}
+
+func (t *pageTracker) add(page int, mediaCount int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if mediaCount > 0 {
+		t.pending[page] += mediaCount
+	}
+	if page > t.dispatched {
+		t.dispatched = page
+	}
+}
+
+func (t *pageTracker) done(page int, mediaCount int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[page] -= mediaCount
+	if t.pending[page] <= 0 {
+		delete(t.pending, page)
+	}
+}
+
+func (t *pageTracker) lastCompleted() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.pending) == 0 {
+		return t.dispatched
+	}
+
+	lowest := t.dispatched
+	for page := range t.pending {
+		lowest = min(lowest, page)
+	}
+	return lowest - 1
+}
+
+func toInts(values []int32) []int {
+	ints := make([]int, len(values))
+	for i, v := range values {
+		ints[i] = int(v)
+	}
+	return ints
+}
+
+func toInt32s(values []int) []int32 {
+	ints := make([]int32, len(values))
+	for i, v := range values {
+		ints[i] = int32(v)
+	}
+	return ints
+}
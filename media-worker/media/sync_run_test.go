@@ -0,0 +1,130 @@
+package media
+
+import "testing"
+
+func TestPageTrackerLastCompleted(t *testing.T) {
+	type step struct {
+		done       bool
+		page       int
+		mediaCount int
+	}
+
+	tests := []struct {
+		name       string
+		startAfter int
+		steps      []step
+		want       int
+	}{
+		{
+			name: "nothing dispatched",
+			want: 0,
+		},
+		{
+			name:       "resumed with nothing dispatched",
+			startAfter: 7,
+			want:       7,
+		},
+		{
+			name: "every page written",
+			steps: []step{
+				{page: 1, mediaCount: 50},
+				{page: 2, mediaCount: 50},
+				{done: true, page: 1, mediaCount: 50},
+				{done: true, page: 2, mediaCount: 50},
+			},
+			want: 2,
+		},
+		{
+			name: "later page finishes first",
+			steps: []step{
+				{page: 1, mediaCount: 50},
+				{page: 2, mediaCount: 50},
+				{page: 3, mediaCount: 50},
+				{done: true, page: 3, mediaCount: 50},
+				{done: true, page: 2, mediaCount: 50},
+			},
+			want: 0,
+		},
+		{
+			name: "earliest pending page holds back the rest",
+			steps: []step{
+				{page: 1, mediaCount: 50},
+				{page: 2, mediaCount: 50},
+				{page: 3, mediaCount: 50},
+				{done: true, page: 1, mediaCount: 50},
+				{done: true, page: 3, mediaCount: 50},
+			},
+			want: 1,
+		},
+		{
+			name: "partly written page stays pending",
+			steps: []step{
+				{page: 1, mediaCount: 50},
+				{page: 2, mediaCount: 50},
+				{done: true, page: 1, mediaCount: 50},
+				{done: true, page: 2, mediaCount: 49},
+			},
+			want: 1,
+		},
+		{
+			name: "media written one by one after a failed bulk insert",
+			steps: []step{
+				{page: 1, mediaCount: 2},
+				{done: true, page: 1, mediaCount: 1},
+				{done: true, page: 1, mediaCount: 1},
+			},
+			want: 1,
+		},
+		{
+			// failed pages are added with no media, they are retried from failed_pages instead
+			name: "failed page does not hold back later pages",
+			steps: []step{
+				{page: 1, mediaCount: 50},
+				{page: 2, mediaCount: 0},
+				{page: 3, mediaCount: 50},
+				{done: true, page: 1, mediaCount: 50},
+				{done: true, page: 3, mediaCount: 50},
+			},
+			want: 3,
+		},
+		{
+			name:       "resumed run counts from its checkpoint",
+			startAfter: 10,
+			steps: []step{
+				{page: 11, mediaCount: 50},
+				{page: 12, mediaCount: 50},
+				{done: true, page: 12, mediaCount: 50},
+			},
+			want: 10,
+		},
+		{
+			name: "retried failed page below the checkpoint",
+			steps: []step{
+				{page: 1, mediaCount: 50},
+				{page: 2, mediaCount: 0},
+				{page: 3, mediaCount: 50},
+				{done: true, page: 1, mediaCount: 50},
+				{done: true, page: 3, mediaCount: 50},
+				{page: 2, mediaCount: 50},
+			},
+			want: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := newPageTracker(test.startAfter)
+			for _, step := range test.steps {
+				if step.done {
+					tracker.done(step.page, step.mediaCount)
+				} else {
+					tracker.add(step.page, step.mediaCount)
+				}
+			}
+
+			if got := tracker.lastCompleted(); got != test.want {
+				t.Errorf("lastCompleted() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
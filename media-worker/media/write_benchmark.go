@@ -0,0 +1,68 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// BenchmarkWriters fetches the first pages of DiscoverMedia once and times writing them
+// through the per-row upserts and through the bulk staging path. Both upsert real rows, the
+// content hashes are cleared before each pass so neither gets away with only a hash check.
+func BenchmarkWriters(ctx context.Context, url string, pages int) error {
+	pool, err := NewPool(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	q := database.New(pool)
+	handler := NewGraphQLHandler(url, RequestTimeout, sharedLimiter)
+
+	var batches [][]MediaDetails
+	var ids []int32
+	total := 0
+	for page := 1; page <= pages; page++ {
+		response, err := fetchPage(ctx, handler, DiscoverMedia, page, nil)
+		if err != nil {
+			return err
+		}
+		batches = append(batches, response.Page.Media)
+		for _, media := range response.Page.Media {
+			ids = append(ids, int32(media.ID))
+		}
+		total += len(response.Page.Media)
+	}
+
+	if err := q.ClearMediaContentHashes(ctx, ids); err != nil {
+		return err
+	}
+	start := time.Now()
+	for _, batch := range batches {
+		for _, media := range batch {
+			if err := insertMedia(ctx, pool, q, media, pgtype.Int8{}); err != nil {
+				return err
+			}
+		}
+	}
+	rowElapsed := time.Since(start)
+
+	if err := q.ClearMediaContentHashes(ctx, ids); err != nil {
+		return err
+	}
+	start = time.Now()
+	for _, batch := range batches {
+		if err := bulkInsertMedia(ctx, pool, batch, pgtype.Int8{}); err != nil {
+			return err
+		}
+	}
+	bulkElapsed := time.Since(start)
+
+	fmt.Printf("Wrote %d media from %d pages\n", total, len(batches))
+	fmt.Printf("per-row: %s (%.1f media/s)\n", rowElapsed, float64(total)/rowElapsed.Seconds())
+	fmt.Printf("bulk:    %s (%.1f media/s)\n", bulkElapsed, float64(total)/bulkElapsed.Seconds())
+	fmt.Printf("bulk is %.1fx faster\n", rowElapsed.Seconds()/bulkElapsed.Seconds())
+	return nil
+}
@@ -0,0 +1,12 @@
+DROP TABLE watchlist;
+DROP TABLE media_details;
+DROP TABLE media;
+DROP TABLE users;
+
+DROP TYPE media_type;
+DROP TYPE watchlist_status;
+DROP TYPE score_distribution;
+DROP TYPE recommendation;
+DROP TYPE airing_schedule;
+DROP TYPE fuzzy_date;
+DROP TYPE titles;
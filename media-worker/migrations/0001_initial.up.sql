@@ -49,20 +49,6 @@ CREATE TABLE users
     created_at    TIMESTAMPTZ      DEFAULT NOW()
 );
 
-CREATE TABLE watchlist
-(
-    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    user_id       UUID REFERENCES users (id) ON DELETE CASCADE,
-    media_id      INTEGER REFERENCES media (id),
-    private       BOOLEAN          DEFAULT FALSE,
-    status        watchlist_status,
-    score         INTEGER,
-    progress      INTEGER,
-    rewatch_count INTEGER,
-    start_date    TIMESTAMP,
-    end_date      TIMESTAMP
-);
-
 CREATE TABLE media
 (
     id            INTEGER PRIMARY KEY,
@@ -100,4 +86,18 @@ CREATE TABLE media_details
     airing_schedule    airing_schedule,
     recommendations    recommendation[],
     score_distribution score_distribution[]
-);
\ No newline at end of file
+);
+
+CREATE TABLE watchlist
+(
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID REFERENCES users (id) ON DELETE CASCADE,
+    media_id      INTEGER REFERENCES media (id),
+    private       BOOLEAN          DEFAULT FALSE,
+    status        watchlist_status,
+    score         INTEGER,
+    progress      INTEGER,
+    rewatch_count INTEGER,
+    start_date    TIMESTAMP,
+    end_date      TIMESTAMP
+);
@@ -0,0 +1,12 @@
+CREATE TABLE sync_runs
+(
+    id                  BIGSERIAL PRIMARY KEY,
+    mode                TEXT                          NOT NULL,
+    status              TEXT        DEFAULT 'running' NOT NULL,
+    last_completed_page INTEGER     DEFAULT 0         NOT NULL,
+    failed_pages        INTEGER[]   DEFAULT '{}'      NOT NULL,
+    failed_ids          INTEGER[]   DEFAULT '{}'      NOT NULL,
+    started_at          TIMESTAMPTZ DEFAULT NOW()     NOT NULL,
+    updated_at          TIMESTAMPTZ DEFAULT NOW()     NOT NULL,
+    finished_at         TIMESTAMPTZ
+);
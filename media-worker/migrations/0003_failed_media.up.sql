@@ -0,0 +1,9 @@
+CREATE TABLE failed_media
+(
+    media_id        INTEGER PRIMARY KEY,
+    error           TEXT                      NOT NULL,
+    attempts        INTEGER     DEFAULT 1     NOT NULL,
+    payload         JSONB,
+    first_failed_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    last_failed_at  TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
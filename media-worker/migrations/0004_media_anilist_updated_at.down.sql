@@ -0,0 +1,2 @@
+ALTER TABLE media
+    DROP COLUMN anilist_updated_at;
@@ -0,0 +1,2 @@
+ALTER TABLE media
+    ADD COLUMN anilist_updated_at BIGINT;
@@ -0,0 +1,3 @@
+ALTER TABLE media
+    DROP COLUMN content_hash,
+    DROP COLUMN last_checked;
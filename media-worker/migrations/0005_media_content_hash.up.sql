@@ -0,0 +1,3 @@
+ALTER TABLE media
+    ADD COLUMN content_hash TEXT,
+    ADD COLUMN last_checked TIMESTAMPTZ DEFAULT NOW() NOT NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE media_history
+(
+    id          BIGSERIAL PRIMARY KEY,
+    media_id    INTEGER                   NOT NULL REFERENCES media ON DELETE CASCADE,
+    field       TEXT                      NOT NULL,
+    old_value   TEXT,
+    new_value   TEXT,
+    observed_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    sync_run_id BIGINT REFERENCES sync_runs ON DELETE SET NULL
+);
+
+CREATE INDEX media_history_media_id_idx ON media_history (media_id, observed_at);
@@ -0,0 +1,10 @@
+CREATE TABLE media_snapshots
+(
+    media_id      INTEGER           NOT NULL REFERENCES media ON DELETE CASCADE,
+    snapshot_date DATE              NOT NULL,
+    average_score INTEGER,
+    popularity    INTEGER DEFAULT 0 NOT NULL,
+    trending      INTEGER DEFAULT 0 NOT NULL,
+    favourites    INTEGER DEFAULT 0 NOT NULL,
+    PRIMARY KEY (media_id, snapshot_date)
+);
@@ -0,0 +1,4 @@
+DROP TABLE media_genres;
+DROP TABLE media_studios;
+DROP TABLE genres;
+DROP TABLE studios;
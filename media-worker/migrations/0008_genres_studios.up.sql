@@ -0,0 +1,44 @@
+CREATE TABLE studios
+(
+    id                  INTEGER PRIMARY KEY,
+    name                TEXT                  NOT NULL,
+    is_animation_studio BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+CREATE TABLE genres
+(
+    id   SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE media_studios
+(
+    media_id  INTEGER NOT NULL REFERENCES media ON DELETE CASCADE,
+    studio_id INTEGER NOT NULL REFERENCES studios ON DELETE CASCADE,
+    PRIMARY KEY (media_id, studio_id)
+);
+
+CREATE INDEX media_studios_studio_id_idx ON media_studios (studio_id);
+
+CREATE TABLE media_genres
+(
+    media_id INTEGER NOT NULL REFERENCES media ON DELETE CASCADE,
+    genre_id INTEGER NOT NULL REFERENCES genres ON DELETE CASCADE,
+    PRIMARY KEY (media_id, genre_id)
+);
+
+CREATE INDEX media_genres_genre_id_idx ON media_genres (genre_id);
+
+-- genres are plain names so they can be carried over, studios need AniList ids and fill in on the next sync
+INSERT INTO genres (name)
+SELECT DISTINCT UNNEST(genres)
+FROM media
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO media_genres (media_id, genre_id)
+SELECT media.id, genres.id
+FROM media
+         CROSS JOIN LATERAL UNNEST(media.genres) AS genre_name
+         JOIN genres
+              ON genres.name = genre_name
+ON CONFLICT DO NOTHING;
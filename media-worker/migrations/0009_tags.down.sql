@@ -0,0 +1,2 @@
+DROP TABLE media_tags;
+DROP TABLE tags;
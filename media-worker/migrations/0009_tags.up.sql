@@ -0,0 +1,20 @@
+CREATE TABLE tags
+(
+    id       INTEGER PRIMARY KEY,
+    name     TEXT                  NOT NULL,
+    category TEXT,
+    is_adult BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+CREATE INDEX tags_name_idx ON tags (name);
+
+CREATE TABLE media_tags
+(
+    media_id         INTEGER               NOT NULL REFERENCES media ON DELETE CASCADE,
+    tag_id           INTEGER               NOT NULL REFERENCES tags ON DELETE CASCADE,
+    rank             INTEGER               NOT NULL,
+    is_media_spoiler BOOLEAN DEFAULT FALSE NOT NULL,
+    PRIMARY KEY (media_id, tag_id)
+);
+
+CREATE INDEX media_tags_tag_id_rank_idx ON media_tags (tag_id, rank);
@@ -0,0 +1,10 @@
+-- related_id has no foreign key, AniList links to media the worker may not have stored yet
+CREATE TABLE media_relations
+(
+    media_id      INTEGER NOT NULL REFERENCES media ON DELETE CASCADE,
+    related_id    INTEGER NOT NULL,
+    relation_type TEXT    NOT NULL,
+    PRIMARY KEY (media_id, related_id)
+);
+
+CREATE INDEX media_relations_related_id_idx ON media_relations (related_id);
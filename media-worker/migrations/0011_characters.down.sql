@@ -0,0 +1,5 @@
+DROP TABLE credit_syncs;
+DROP TABLE character_voice_actors;
+DROP TABLE media_characters;
+DROP TABLE staff;
+DROP TABLE characters;
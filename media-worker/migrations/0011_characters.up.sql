@@ -0,0 +1,48 @@
+CREATE TABLE characters
+(
+    id          INTEGER PRIMARY KEY,
+    name_full   TEXT,
+    name_native TEXT,
+    image       TEXT
+);
+
+CREATE TABLE staff
+(
+    id          INTEGER PRIMARY KEY,
+    name_full   TEXT,
+    name_native TEXT,
+    image       TEXT
+);
+
+-- position keeps AniList's role ordering, main characters first
+CREATE TABLE media_characters
+(
+    media_id     INTEGER NOT NULL REFERENCES media ON DELETE CASCADE,
+    character_id INTEGER NOT NULL REFERENCES characters ON DELETE CASCADE,
+    role         TEXT    NOT NULL,
+    position     INTEGER NOT NULL,
+    PRIMARY KEY (media_id, character_id)
+);
+
+CREATE INDEX media_characters_character_id_idx ON media_characters (character_id);
+
+CREATE TABLE character_voice_actors
+(
+    media_id     INTEGER NOT NULL,
+    character_id INTEGER NOT NULL,
+    staff_id     INTEGER NOT NULL REFERENCES staff ON DELETE CASCADE,
+    language     TEXT    NOT NULL,
+    PRIMARY KEY (media_id, character_id, staff_id),
+    FOREIGN KEY (media_id, character_id) REFERENCES media_characters ON DELETE CASCADE
+);
+
+CREATE INDEX character_voice_actors_staff_id_idx ON character_voice_actors (staff_id);
+
+-- credit_syncs records when the per-media credit queries last ran, credit is e.g. 'characters'
+CREATE TABLE credit_syncs
+(
+    media_id  INTEGER                   NOT NULL REFERENCES media ON DELETE CASCADE,
+    credit    TEXT                      NOT NULL,
+    synced_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (media_id, credit)
+);
@@ -0,0 +1,11 @@
+-- a staff member can hold several roles on one media, e.g. director and storyboard
+CREATE TABLE media_staff
+(
+    media_id INTEGER NOT NULL REFERENCES media ON DELETE CASCADE,
+    staff_id INTEGER NOT NULL REFERENCES staff ON DELETE CASCADE,
+    role     TEXT    NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (media_id, staff_id, role)
+);
+
+CREATE INDEX media_staff_staff_id_idx ON media_staff (staff_id);
@@ -0,0 +1 @@
+DROP TABLE airing_episodes;
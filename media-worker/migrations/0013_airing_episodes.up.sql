@@ -0,0 +1,14 @@
+-- airing_at is a unix timestamp like AniList's airingAt, previous_airing_at and rescheduled_at
+-- are set when a later sync moves an episode
+CREATE TABLE airing_episodes
+(
+    media_id           INTEGER                   NOT NULL REFERENCES media ON DELETE CASCADE,
+    episode            INTEGER                   NOT NULL,
+    airing_at          BIGINT                    NOT NULL,
+    previous_airing_at BIGINT,
+    rescheduled_at     TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (media_id, episode)
+);
+
+CREATE INDEX airing_episodes_airing_at_idx ON airing_episodes (airing_at);
@@ -0,0 +1,10 @@
+-- who holds each sync lock, the lock itself is a session advisory lock so a crashed holder
+-- frees it straight away and only leaves this row behind until the next holder replaces it
+CREATE TABLE sync_locks
+(
+    name        TEXT PRIMARY KEY,
+    holder      TEXT                      NOT NULL,
+    command     TEXT                      NOT NULL,
+    backend_pid INTEGER                   NOT NULL,
+    acquired_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE airing_episodes
+    DROP COLUMN reschedule_count;
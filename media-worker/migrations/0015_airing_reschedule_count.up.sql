@@ -0,0 +1,7 @@
+-- reschedule_count goes up every time an episode moves, calendar feeds use it as the SEQUENCE
+ALTER TABLE airing_episodes
+    ADD COLUMN reschedule_count INTEGER DEFAULT 0 NOT NULL;
+
+UPDATE airing_episodes
+SET reschedule_count = 1
+WHERE previous_airing_at IS NOT NULL;
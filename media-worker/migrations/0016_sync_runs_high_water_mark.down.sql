@@ -0,0 +1,2 @@
+ALTER TABLE sync_runs
+    DROP COLUMN high_water_mark;
@@ -0,0 +1,4 @@
+-- high_water_mark is the newest AniList updatedAt an incremental run saw, the next run stops
+-- once it pages below it
+ALTER TABLE sync_runs
+    ADD COLUMN high_water_mark BIGINT;
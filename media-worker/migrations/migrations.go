@@ -0,0 +1,211 @@
+// Package migrations holds the versioned database schema, embedded so the worker can bring a
+// database up to date on its own. sqlc reads the same files, down migrations are ignored there.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// migrateLockKey serialises migrations across workers, it is an arbitrary constant
+const migrateLockKey = 7_314_950_001
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    INTEGER PRIMARY KEY,
+    name       TEXT                      NOT NULL,
+    applied_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+)`
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt time.Time
+	Applied   bool
+}
+
+// Load returns every embedded migration sorted by version
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		contents, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration, each in its own transaction
+func Up(ctx context.Context, conn *pgx.Conn) error {
+	return withLock(ctx, conn, func(applied map[int]time.Time, migrations []Migration) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			fmt.Printf("Applying migration %d_%s\n", migration.Version, migration.Name)
+			if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the latest steps applied migrations
+func Down(ctx context.Context, conn *pgx.Conn, steps int) error {
+	return withLock(ctx, conn, func(applied map[int]time.Time, migrations []Migration) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			fmt.Printf("Reverting migration %d_%s\n", migration.Version, migration.Name)
+			if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Baseline marks migrations up to version as applied without running them, for databases
+// that were created from the old unversioned schema
+func Baseline(ctx context.Context, conn *pgx.Conn, version int) error {
+	return withLock(ctx, conn, func(applied map[int]time.Time, migrations []Migration) error {
+		for _, migration := range migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			fmt.Printf("Marking migration %d_%s as applied\n", migration.Version, migration.Name)
+			if _, err := conn.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List reports every known migration and whether it has been applied
+func List(ctx context.Context, conn *pgx.Conn) ([]Status, error) {
+	var statuses []Status
+	err := withLock(ctx, conn, func(applied map[int]time.Time, migrations []Migration) error {
+		for _, migration := range migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, Status{Migration: migration, AppliedAt: appliedAt, Applied: ok})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock holds the migration advisory lock while fn runs against the current state
+func withLock(ctx context.Context, conn *pgx.Conn, fn func(applied map[int]time.Time, migrations []Migration) error) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrateLockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrateLockKey)
+
+	if _, err := conn.Exec(ctx, createSchemaMigrations); err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int32
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return err
+		}
+		applied[int(version)] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return fn(applied, migrations)
+}
@@ -0,0 +1,191 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression, or a fixed interval when written as @every <duration>
+type Schedule struct {
+	spec    string
+	every   time.Duration
+	minutes [60]bool
+	hours   [24]bool
+	days    [32]bool
+	months  [13]bool
+	weekday [7]bool
+	// cron matches a day when either field matches if both are restricted
+	anyDay, anyWeekday bool
+}
+
+var shorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Parse reads the standard five field `minute hour day month weekday` cron syntax with *, lists,
+// ranges and steps, the @hourly style shorthands and @every <duration>
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	s := Schedule{spec: spec}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		if every < time.Minute {
+			return Schedule{}, fmt.Errorf("schedule %q: interval must be at least a minute", spec)
+		}
+		s.every = every
+		return s, nil
+	}
+	expr := spec
+	if full, ok := shorthands[spec]; ok {
+		expr = full
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	ranges := []struct {
+		lo, hi int
+		set    []bool
+	}{
+		{0, 59, s.minutes[:]},
+		{0, 23, s.hours[:]},
+		{1, 31, s.days[:]},
+		{1, 12, s.months[:]},
+	}
+	for i, r := range ranges {
+		if err := parseField(fields[i], r.lo, r.hi, r.set); err != nil {
+			return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+	}
+	if err := parseWeekday(fields[4], s.weekday[:]); err != nil {
+		return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+	}
+	s.anyDay = fields[2] == "*"
+	s.anyWeekday = fields[4] == "*"
+
+	return s, nil
+}
+
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// Next is the first time strictly after t the schedule fires, cron schedules fire on whole minutes
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	// steps go through time.Date since Truncate rounds in UTC, which is off in half hour zones,
+	// stepping the wall clock also makes the hour repeated when DST ends fire only once
+	loc := t.Location()
+	next := after(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc))
+	// every valid expression matches within a leap cycle, the bound only guards against Feb 30th
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		if !s.months[next.Month()] {
+			next = after(next, time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.dayMatches(next) {
+			next = after(next, time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.hours[next.Hour()] {
+			next = after(next, time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, loc))
+			continue
+		}
+		if !s.minutes[next.Minute()] {
+			next = after(next, time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), next.Minute()+1, 0, 0, loc))
+			continue
+		}
+		return next
+	}
+	return time.Time{}
+}
+
+// after keeps the walk moving forward when time.Date lands inside a DST gap and normalises
+// the wall clock to before t
+func after(t, candidate time.Time) time.Time {
+	if candidate.After(t) {
+		return candidate
+	}
+	return t.Add(time.Minute)
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	day, weekday := s.days[t.Day()], s.weekday[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// parseWeekday accepts 0 to 7 with both 0 and 7 meaning Sunday
+func parseWeekday(field string, set []bool) error {
+	var days [8]bool
+	if err := parseField(field, 0, 7, days[:]); err != nil {
+		return err
+	}
+	copy(set, days[:7])
+	set[0] = set[0] || days[7]
+	return nil
+}
+
+// parseField marks the values a comma separated list of *, n, a-b and any of those with /step matches
+func parseField(field string, lo, hi int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		start, end := lo, hi
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if start, err = strconv.Atoi(from); err != nil {
+				return fmt.Errorf("invalid value in %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(to); err != nil {
+					return fmt.Errorf("invalid value in %q", part)
+				}
+			} else if hasStep {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return fmt.Errorf("%q is outside %d-%d", part, lo, hi)
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	kolkata := mustLoad(t, "Asia/Kolkata")
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "every 30 minutes",
+			spec: "*/30 * * * *",
+			from: time.Date(2026, 10, 18, 10, 17, 30, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC),
+				time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "strictly after a matching time",
+			spec: "0 4 * * *",
+			from: time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 10, 19, 4, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "every 6 hours in a half hour zone",
+			spec: "0 */6 * * *",
+			from: time.Date(2026, 10, 18, 10, 17, 0, 0, kolkata),
+			want: []time.Time{
+				time.Date(2026, 10, 18, 12, 0, 0, 0, kolkata),
+				time.Date(2026, 10, 18, 18, 0, 0, 0, kolkata),
+			},
+		},
+		{
+			name: "daily in a half hour zone",
+			spec: "0 4 * * *",
+			from: time.Date(2026, 10, 18, 10, 17, 0, 0, kolkata),
+			want: []time.Time{time.Date(2026, 10, 19, 4, 0, 0, 0, kolkata)},
+		},
+		{
+			name: "spring forward skips the missing hour",
+			spec: "30 2 * * *",
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+			},
+		},
+		{
+			name: "hourly across spring forward",
+			spec: "0 * * * *",
+			from: time.Date(2026, 3, 8, 1, 10, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 3, 0, 0, 0, newYork),
+				time.Date(2026, 3, 8, 4, 0, 0, 0, newYork),
+			},
+		},
+		{
+			name: "fall back fires on the first 1am",
+			spec: "0 1 * * *",
+			from: time.Date(2026, 10, 31, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 1, 0, 0, 0, newYork),
+				time.Date(2026, 11, 2, 1, 0, 0, 0, newYork),
+			},
+		},
+		{
+			name: "feb 29 waits for a leap year",
+			spec: "0 0 29 2 *",
+			from: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2032, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "day of month or day of week when both are set",
+			spec: "0 0 13 * 5",
+			from: time.Date(2026, 11, 10, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 11, 13, 0, 0, 0, 0, time.UTC), // friday the 13th
+				time.Date(2026, 11, 20, 0, 0, 0, 0, time.UTC), // friday
+				time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC), // friday
+				time.Date(2026, 12, 4, 0, 0, 0, 0, time.UTC),  // friday
+				time.Date(2026, 12, 11, 0, 0, 0, 0, time.UTC), // friday
+				time.Date(2026, 12, 13, 0, 0, 0, 0, time.UTC), // sunday the 13th
+			},
+		},
+		{
+			name: "day of week only",
+			spec: "15 9 * * 1-5",
+			from: time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 10, 19, 9, 15, 0, 0, time.UTC),
+				time.Date(2026, 10, 20, 9, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "sunday as 7",
+			spec: "0 0 * * 7",
+			from: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "weekly shorthand",
+			spec: "@weekly",
+			from: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "every keeps the offset from the previous run",
+			spec: "@every 90m",
+			from: time.Date(2026, 10, 18, 10, 17, 30, 0, kolkata),
+			want: []time.Time{
+				time.Date(2026, 10, 18, 11, 47, 30, 0, kolkata),
+				time.Date(2026, 10, 18, 13, 17, 30, 0, kolkata),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+
+			from := tt.from
+			for i, want := range tt.want {
+				got := s.Next(from)
+				if !got.Equal(want) {
+					t.Fatalf("Next #%d after %s = %s, want %s", i+1, from, got, want)
+				}
+				from = got
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-2 * * * *",
+		"a * * * *",
+		"@every 30s",
+		"@every soon",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"media-worker/calendar"
+	"media-worker/database"
+	"media-worker/media"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type mediaListHook func(ctx context.Context, q *database.Queries, mediaList []int32) error
+
+var commands = []command{
+	{
+		name:    "backfill",
+		summary: "walk every page of AniList into the database",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			resume := fs.Bool("resume", false, "continue the last unfinished backfill from its checkpoint")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				return media.BackfillMedia(ctx, anilistURL, *resume)
+			}
+		},
+	},
+	{
+		name:    "new",
+		summary: "add media released since the last run, newest first until a page is all known media",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			pageCap := fs.Int("page-cap", media.NewMediaPageCap, "most pages walked before giving up on finding known media")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				media.NewMediaPageCap = *pageCap
+				return media.UpdateNewMedia(ctx, anilistURL)
+			}
+		},
+	},
+	{
+		name:    "refresh",
+		summary: "re-fetch the high priority (airing and recent) or low priority (finished) media",
+		sync:    true,
+		ownLock: true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			priority := fs.String("priority", "", "which media to refresh, high or low")
+			dailyDays := fs.Int("snapshot-daily-days", media.SnapshotDailyDays, "days daily snapshots are kept before being downsampled to weekly")
+			weeklyDays := fs.Int("snapshot-weekly-days", media.SnapshotWeeklyDays, "days weekly snapshots are kept before being downsampled to monthly")
+			airedDays := fs.Int("aired-days", media.AiredWindowDays, "days of aired episodes whose air times a high priority refresh corrects")
+			validate := func(args []string) error {
+				if *priority != "high" && *priority != "low" {
+					return fmt.Errorf("%w: --priority must be high or low, got %q", errUsage, *priority)
+				}
+				return nil
+			}
+			return validate, func(ctx context.Context, args []string) (media.Result, error) {
+				media.SnapshotDailyDays = *dailyDays
+				media.SnapshotWeeklyDays = *weeklyDays
+				media.AiredWindowDays = *airedDays
+
+				pool, err := media.NewPool(ctx)
+				if err != nil {
+					return media.Result{}, err
+				}
+				defer pool.Close()
+
+				return withLock(ctx, "refresh "+*priority, func() (media.Result, error) {
+					return refresh(ctx, pool, *priority)
+				})
+			}
+		},
+	},
+	{
+		name:    "incremental",
+		summary: "re-fetch media changed on AniList since they were last stored",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			pageCap := fs.Int("page-cap", media.IncrementalPageCap, "most pages walked before giving up on catching up")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				media.IncrementalPageCap = *pageCap
+				return media.UpdateChangedMedia(ctx, anilistURL)
+			}
+		},
+	},
+	{
+		name:    "fetch",
+		args:    "<id...> | -",
+		summary: "fetch and store specific AniList media ids, read from stdin when given - or piped",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			var ids []int32
+			validate := func(args []string) (err error) {
+				ids, err = fetchIds(args, os.Stdin)
+				return err
+			}
+			return validate, func(ctx context.Context, args []string) (media.Result, error) {
+				statuses, err := media.SyncIDs(ctx, anilistURL, ids)
+				if statuses == nil {
+					return media.Result{}, err
+				}
+
+				var result media.Result
+				skipped := 0
+				for _, status := range statuses {
+					if status.Err != nil {
+						fmt.Printf("%d %s: %s\n", status.ID, status.Outcome, status.Err)
+					} else {
+						fmt.Printf("%d %s\n", status.ID, status.Outcome)
+					}
+					if status.Outcome == media.SyncSkipped {
+						skipped++
+					}
+					if status.Outcome != media.SyncStored {
+						result.FailedIds = append(result.FailedIds, int(status.ID))
+					}
+				}
+				// statuses has one entry per distinct id
+				fmt.Printf("Stored %d of %d media\n", len(statuses)-len(result.FailedIds), len(statuses))
+				if skipped > 0 {
+					fmt.Printf("%d media were not attempted\n", skipped)
+				}
+				return result, err
+			}
+		},
+	},
+	{
+		name:    "retry-failed",
+		summary: "re-fetch the media that failed to insert, clearing the ones that succeed",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				return media.RetryFailedMedia(ctx, anilistURL)
+			}
+		},
+	},
+	{
+		name:    "characters",
+		summary: "store characters and Japanese voice actors, least recently synced media first",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			mediaCap := fs.Int("media-cap", media.CharacterMediaCap, "media visited per run")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				media.CharacterMediaCap = *mediaCap
+				return media.UpdateCharacters(ctx, anilistURL)
+			}
+		},
+	},
+	{
+		name:    "daemon",
+		summary: "keep running and do the refreshes and new media discovery on cron schedules, until SIGTERM",
+		sync:    true,
+		ownLock: true,
+		setup:   daemonCommand,
+	},
+	{
+		name:    "calendar",
+		summary: "write .ics airing feeds per media, season and watchlist from stored episodes",
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			dir := fs.String("dir", "calendars", "directory the feeds are written to")
+			pastDays := fs.Int("past-days", calendar.PastDays, "days of aired episodes kept in each feed")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				calendar.PastDays = *pastDays
+				return media.Result{}, writeCalendars(ctx, *dir)
+			}
+		},
+	},
+	{
+		name:    "bench-write",
+		summary: "time the per-row writer against the bulk writer on real pages",
+		sync:    true,
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			pages := fs.Int("pages", 4, "pages fetched before timing the writers")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				return media.Result{}, media.BenchmarkWriters(ctx, anilistURL, *pages)
+			}
+		},
+	},
+	{
+		name:    "migrate",
+		args:    "up | down [steps] | status | baseline <version>",
+		summary: "apply or roll back the embedded schema migrations",
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				return media.Result{}, migrate(ctx, args)
+			}
+		},
+	},
+	{
+		name:    "status",
+		summary: "show migrations, sync lock holders, recent sync runs and dead-lettered media",
+		setup: func(fs *flag.FlagSet) (validator, runFunc) {
+			runs := fs.Int("runs", 5, "recent sync runs shown")
+			return nil, func(ctx context.Context, args []string) (media.Result, error) {
+				return media.Result{}, status(ctx, *runs)
+			}
+		},
+	},
+}
+
+// refresh re-fetches the high or low priority media, high priority ones are snapshotted afterwards
+// and the air times of recently aired episodes are corrected
+func refresh(ctx context.Context, pool *pgxpool.Pool, priority string) (media.Result, error) {
+	q := database.New(pool)
+
+	get, after := q.QueryLowPrioMedia, mediaListHook(nil)
+	if priority == "high" {
+		get = q.QueryHighPrioMedia
+		after = func(ctx context.Context, q *database.Queries, mediaList []int32) error {
+			if err := media.RecordSnapshots(ctx, q, mediaList); err != nil {
+				return err
+			}
+			return media.UpdateAiredEpisodes(ctx, pool, anilistURL)
+		}
+	}
+
+	mediaList, err := get(ctx)
+	if err != nil {
+		return media.Result{}, err
+	}
+	if mediaList == nil {
+		mediaList = []int32{}
+	}
+
+	fmt.Printf("Updating database with %d media\n", len(mediaList))
+
+	result, err := media.UpdateMedia(ctx, pool, anilistURL, "refresh-"+priority, media.UpdateFromMediaList, mediaList)
+	if after == nil || err != nil {
+		return result, err
+	}
+	return result, after(ctx, q, mediaList)
+}
+
+// fetchIds parses the ids given as arguments, or read from stdin when the only argument is - or
+// when there are none and stdin is not a terminal, ids on stdin may be split by whitespace or commas
+func fetchIds(args []string, stdin *os.File) ([]int32, error) {
+	fields := args
+	if (len(args) == 1 && args[0] == "-") || (len(args) == 0 && !isTerminal(stdin)) {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		fields = strings.FieldsFunc(string(input), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: fetch needs at least one media id", errUsage)
+	}
+
+	ids := make([]int32, len(fields))
+	for i, field := range fields {
+		id, err := strconv.ParseInt(field, 10, 32)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: invalid media id %q", errUsage, field)
+		}
+		ids[i] = int32(id)
+	}
+	return ids, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err != nil || info.Mode()&os.ModeCharDevice != 0
+}
+
+// writeCalendars renders the airing feeds from stored episodes, it never talks to AniList
+func writeCalendars(ctx context.Context, dir string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	return calendar.WriteFeeds(ctx, database.New(conn), dir)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"media-worker/media"
+	"media-worker/schedule"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// daemonJob is one sync the daemon runs on its own schedule
+type daemonJob struct {
+	name     string
+	spec     *string
+	schedule schedule.Schedule
+	run      func(ctx context.Context, pool *pgxpool.Pool) (media.Result, error)
+}
+
+func daemonCommand(fs *flag.FlagSet) (validator, runFunc) {
+	jobs := []*daemonJob{
+		{
+			name: "refresh high",
+			spec: fs.String("high", "*/30 * * * *", "schedule of the high priority refresh, empty disables it"),
+			run: func(ctx context.Context, pool *pgxpool.Pool) (media.Result, error) {
+				return refresh(ctx, pool, "high")
+			},
+		},
+		{
+			name: "new",
+			spec: fs.String("new", "0 */6 * * *", "schedule of new media discovery, empty disables it"),
+			run: func(ctx context.Context, pool *pgxpool.Pool) (media.Result, error) {
+				return media.UpdateNewMediaWithPool(ctx, pool, anilistURL)
+			},
+		},
+		{
+			name: "refresh low",
+			spec: fs.String("low", "0 4 * * *", "schedule of the low priority refresh, empty disables it"),
+			run: func(ctx context.Context, pool *pgxpool.Pool) (media.Result, error) {
+				return refresh(ctx, pool, "low")
+			},
+		},
+	}
+
+	var enabled []*daemonJob
+	validate := func(args []string) error {
+		for _, job := range jobs {
+			if *job.spec == "" {
+				continue
+			}
+			parsed, err := schedule.Parse(*job.spec)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", errUsage, job.name, err)
+			}
+			job.schedule = parsed
+			enabled = append(enabled, job)
+		}
+		if len(enabled) == 0 {
+			return fmt.Errorf("%w: every schedule is disabled", errUsage)
+		}
+		return nil
+	}
+
+	return validate, func(ctx context.Context, args []string) (media.Result, error) {
+		pool, err := media.NewPool(ctx)
+		if err != nil {
+			return media.Result{}, err
+		}
+		defer pool.Close()
+
+		var wg sync.WaitGroup
+		for _, job := range enabled {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				job.loop(ctx, pool)
+			}()
+		}
+		wg.Wait()
+
+		fmt.Println("Daemon stopped")
+		return media.Result{}, nil
+	}
+}
+
+// loop runs the job each time its schedule fires until ctx is cancelled, a run that is still going
+// when the next time comes around makes the job skip that time instead of starting a second copy
+func (j *daemonJob) loop(ctx context.Context, pool *pgxpool.Pool) {
+	next := j.schedule.Next(time.Now())
+	for {
+		if next.IsZero() {
+			log.Printf("%s: schedule %q never fires again", j.name, j.schedule)
+			return
+		}
+		fmt.Printf("Next %s at %s\n", j.name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		fmt.Printf("Starting %s\n", j.name)
+		result, err := withLock(ctx, j.name, func() (media.Result, error) {
+			return j.run(ctx, pool)
+		})
+		switch {
+		case err != nil && ctx.Err() != nil:
+			fmt.Printf("%s stopped by shutdown after %s\n", j.name, time.Since(start))
+			return
+		case err != nil:
+			log.Printf("%s: %v", j.name, err)
+		default:
+			fmt.Printf("%s finished in %s with %d failed pages and %d failed media\n",
+				j.name, time.Since(start), len(result.FailedPages), len(result.FailedIds))
+		}
+
+		next = j.schedule.Next(next)
+		if now := time.Now(); next.Before(now) {
+			missed := next
+			next = j.schedule.Next(now)
+			fmt.Printf("%s overran its %s slot, next run moved to %s\n", j.name, missed.Format(time.RFC3339), next.Format(time.RFC3339))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"media-worker/database"
+	"media-worker/migrations"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func connect(ctx context.Context) (*pgx.Conn, error) {
+	connStr := fmt.Sprintf(
+		"host=%s user=%s password=%s database=%s port=5432",
+		os.Getenv("PG_HOST"),
+		os.Getenv("PG_USER"),
+		os.Getenv("PG_PASSWORD"),
+		os.Getenv("PG_DATABASE"),
+	)
+
+	return pgx.Connect(ctx, connStr)
+}
+
+// migrate runs `migrate up`, `down [steps]`, `status` or `baseline <version>`
+func migrate(ctx context.Context, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	switch action {
+	case "up":
+		return migrations.Up(ctx, conn)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("%w: invalid step count %q", errUsage, args[1])
+			}
+		}
+		return migrations.Down(ctx, conn, steps)
+	case "baseline":
+		if len(args) < 2 {
+			return fmt.Errorf("%w: baseline needs the version the database is already at", errUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("%w: invalid version %q", errUsage, args[1])
+		}
+		return migrations.Baseline(ctx, conn, version)
+	case "status":
+		statuses, err := migrations.List(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown migrate action %q, expected up, down, status or baseline", errUsage, action)
+	}
+}
+
+// status prints a short health summary of the worker's tables
+func status(ctx context.Context, runs int) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	statuses, err := migrations.List(ctx, conn)
+	if err != nil {
+		return err
+	}
+	applied, latest := 0, "none"
+	for _, status := range statuses {
+		if status.Applied {
+			applied++
+			latest = fmt.Sprintf("%04d_%s", status.Version, status.Name)
+		}
+	}
+	fmt.Printf("Migrations: %d applied, %d pending, latest %s\n", applied, len(statuses)-applied, latest)
+
+	q := database.New(conn)
+
+	failed, err := q.CountFailedMedia(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Failed media: %d\n", failed)
+
+	holders, err := q.ListSyncLockHolders(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Sync locks:")
+	if len(holders) == 0 {
+		fmt.Println("  none held")
+	}
+	for _, holder := range holders {
+		state := "held"
+		if !holder.Held {
+			state = "stale"
+		}
+		fmt.Printf("  %-14s %-5s by %s since %s, %s\n",
+			holder.Name, state, holder.Holder, holder.AcquiredAt.Time.Format(time.RFC3339), holder.Command)
+	}
+
+	recent, err := q.ListRecentSyncRuns(ctx, int32(runs))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Recent sync runs:")
+	if len(recent) == 0 {
+		fmt.Println("  none")
+	}
+	for _, run := range recent {
+		finished := "-"
+		if run.FinishedAt.Valid {
+			finished = run.FinishedAt.Time.Format(time.RFC3339)
+		}
+		fmt.Printf("  #%-5d %-8s %-10s last page %-6d failed pages %-4d failed ids %-4d started %s finished %s\n",
+			run.ID, run.Mode, run.Status, run.LastCompletedPage, len(run.FailedPages), len(run.FailedIds),
+			run.StartedAt.Time.Format(time.RFC3339), finished)
+	}
+
+	return nil
+}
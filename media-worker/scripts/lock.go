@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"media-worker/database"
+	"media-worker/media"
+	"os"
+	"strings"
+	"time"
+)
+
+// lockScope is mode to let different modes sync side by side, or global for one sync at a time
+var lockScope = "mode"
+
+// lockHeld is what a run does when another worker holds its lock, wait, skip or fail
+var lockHeld = "skip"
+
+var errLockHeld = errors.New("sync lock is held by another worker")
+
+// lockFlags registers the run lock flags on sync commands
+func lockFlags(fs *flag.FlagSet) {
+	fs.StringVar(&lockScope, "lock", lockScope, "run lock scope, mode locks per sync mode, global allows one sync at a time")
+	fs.StringVar(&lockHeld, "lock-held", lockHeld, "when the run lock is held: wait for it, skip the run or fail")
+}
+
+func validateLockFlags() error {
+	if lockScope != "mode" && lockScope != "global" {
+		return fmt.Errorf("%w: --lock must be mode or global, got %q", errUsage, lockScope)
+	}
+	if lockHeld != "wait" && lockHeld != "skip" && lockHeld != "fail" {
+		return fmt.Errorf("%w: --lock-held must be wait, skip or fail, got %q", errUsage, lockHeld)
+	}
+	return nil
+}
+
+// withLock runs fn while holding the Postgres advisory lock of mode, the lock lives on its own
+// connection so it goes away with the process even if the release below never runs
+func withLock(ctx context.Context, mode string, fn func() (media.Result, error)) (media.Result, error) {
+	name := mode
+	if lockScope == "global" {
+		name = "global"
+	}
+
+	conn, err := connect(ctx)
+	if err != nil {
+		return media.Result{}, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+	q := database.New(conn)
+
+	acquired, err := q.TryAcquireSyncLock(ctx, name)
+	if err != nil {
+		return media.Result{}, err
+	}
+	if !acquired {
+		holder := describeHolder(ctx, q, name)
+		switch lockHeld {
+		case "skip":
+			fmt.Printf("Skipping %s, lock %q is held by %s\n", mode, name, holder)
+			return media.Result{}, nil
+		case "fail":
+			return media.Result{}, fmt.Errorf("%s: %w: lock %q held by %s", mode, errLockHeld, name, holder)
+		}
+
+		fmt.Printf("Waiting for lock %q held by %s\n", name, holder)
+		if err := q.AcquireSyncLock(ctx, name); err != nil {
+			return media.Result{}, err
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	if err := q.RecordSyncLockHolder(ctx, database.RecordSyncLockHolderParams{
+		Name:    name,
+		Holder:  fmt.Sprintf("%s pid %d", hostname, os.Getpid()),
+		Command: mode + ": " + strings.Join(os.Args, " "),
+	}); err != nil {
+		log.Printf("recording holder of lock %q: %v", name, err)
+	}
+
+	defer func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := q.ClearSyncLockHolder(ctx, name); err != nil {
+			log.Printf("clearing holder of lock %q: %v", name, err)
+		}
+		if _, err := q.ReleaseSyncLock(ctx, name); err != nil {
+			log.Printf("releasing lock %q: %v", name, err)
+		}
+	}()
+
+	return fn()
+}
+
+func describeHolder(ctx context.Context, q *database.Queries, name string) string {
+	holders, err := q.ListSyncLockHolders(ctx)
+	if err != nil {
+		return "an unknown worker"
+	}
+	for _, holder := range holders {
+		if holder.Name == name && holder.Held {
+			return fmt.Sprintf("%s (%s) since %s", holder.Holder, holder.Command, holder.AcquiredAt.Time.Format(time.RFC3339))
+		}
+	}
+	return "an unknown worker"
+}
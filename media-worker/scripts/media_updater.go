@@ -2,110 +2,254 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"media-worker/database"
 	"media-worker/media"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
 )
 
-type mediaListGetter func(ctx context.Context, q *database.Queries) ([]int32, error)
+const anilistURL = "https://graphql.anilist.co"
+
+// exit codes, exitPartial means the run finished but left failed pages or media behind
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitPartial = 3
+)
+
+// errUsage marks errors caused by how the command was called, they print the command help
+var errUsage = errors.New("usage")
+
+// runFunc runs a command with the positional arguments left after its flags
+type runFunc func(ctx context.Context, args []string) (media.Result, error)
+
+// validator rejects bad arguments before the environment is loaded or the countdown starts
+type validator func(args []string) error
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	// sync commands talk to AniList, they get the request flags, the countdown and the run lock
+	sync bool
+	// ownLock commands take the run lock themselves, the lock name depends on more than the command
+	ownLock bool
+	setup   func(fs *flag.FlagSet) (validator, runFunc)
+}
 
 func main() {
-	mode := flag.String("mode", "", "a string")
-	flag.Parse()
+	os.Exit(run(os.Args[1:]))
+}
 
-	required := []string{"PG_HOST", "PG_USER", "PG_PASSWORD", "PG_DATABASE"}
+func run(args []string) int {
+	args = legacyMode(args)
 
-	for _, key := range required {
-		if os.Getenv(key) == "" {
-			if err := godotenv.Load(".env"); err != nil {
-				log.Panicf("No .env file found with err: %s\n", err.Error())
-			}
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+	if name := args[0]; name == "help" || name == "-h" || name == "--help" {
+		if len(args) > 1 {
+			args = []string{args[1], "-h"}
+		} else {
+			usage(os.Stdout)
+			return exitOK
 		}
 	}
 
-	switch *mode {
-	case "all":
-		fmt.Println("starting db backfill with all media in 3 seconds")
-		time.Sleep(3 * time.Second)
-		uploadAllMedia()
-	case "new":
-		fmt.Println("starting db backfill with new media in 3 seconds")
-		time.Sleep(3 * time.Second)
-		uploadNewMedia()
-	case "high":
-		fmt.Println("updating high priority media in 3 seconds")
-		time.Sleep(3 * time.Second)
-		updateHighPrioMedia()
-	case "low":
-		fmt.Println("updating low priority media in 3 seconds")
-		time.Sleep(3 * time.Second)
-		updateLowPrioMedia()
-	default:
-		fmt.Println("Invalid mode, please only enter either: 'all' or 'new'")
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
 	}
-}
 
-// TODO instead of just getting the first 4 page, go until an id from query is already in db
-func uploadNewMedia() {
-	media.UpdatePage("https://graphql.anilist.co", media.DiscoverNewMedia, []int{1, 2, 3, 4})
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() { commandUsage(fs.Output(), fs, cmd) }
+
+	var yes *bool
+	var applySyncFlags func()
+	if cmd.sync {
+		yes = fs.Bool("yes", false, "start right away instead of after a 3 second countdown")
+		applySyncFlags = syncFlags(fs)
+		lockFlags(fs)
+	}
+	validate, runner := cmd.setup(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if validate != nil {
+		if err := validate(fs.Args()); err != nil {
+			return usageFailure(fs, cmd, err)
+		}
+	}
+	if applySyncFlags != nil {
+		if err := validateLockFlags(); err != nil {
+			return usageFailure(fs, cmd, err)
+		}
+		applySyncFlags()
+	}
+
+	loadEnv()
+
+	// ECS stops tasks with SIGTERM, cancel in-flight work instead of dying mid-insert
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cmd.sync && !*yes {
+		fmt.Printf("starting %s in 3 seconds, pass --yes to skip the wait\n", cmd.name)
+		select {
+		case <-time.After(3 * time.Second):
+		case <-ctx.Done():
+			return exitError
+		}
+	}
+
+	var result media.Result
+	var err error
+	if cmd.sync && !cmd.ownLock {
+		result, err = withLock(ctx, cmd.name, func() (media.Result, error) {
+			return runner(ctx, fs.Args())
+		})
+	} else {
+		result, err = runner(ctx, fs.Args())
+	}
+	switch {
+	case errors.Is(err, errUsage):
+		return usageFailure(fs, cmd, err)
+	case err != nil:
+		log.Printf("%s: %v", cmd.name, err)
+		return exitError
+	case result.Failed():
+		fmt.Fprintf(os.Stderr, "%s finished with %d failed pages and %d failed media\n",
+			cmd.name, len(result.FailedPages), len(result.FailedIds))
+		return exitPartial
+	}
+	return exitOK
 }
 
-func uploadAllMedia() {
-	media.UpdateMedia("https://graphql.anilist.co", media.DiscoverMedia, nil)
+func usageFailure(fs *flag.FlagSet, cmd command, err error) int {
+	fmt.Fprintf(os.Stderr, "%s\n\n", err)
+	commandUsage(os.Stderr, fs, cmd)
+	return exitUsage
 }
 
-func updateHighPrioMedia() {
-	if err := runUpdate(func(ctx context.Context, q *database.Queries) ([]int32, error) {
-		return q.QueryHighPrioMedia(ctx)
-	}); err != nil {
-		log.Fatal(err)
+// syncFlags registers the AniList request flags, the returned func applies them once parsed
+func syncFlags(fs *flag.FlagSet) func() {
+	timeout := fs.Duration("timeout", media.RequestTimeout, "deadline for a single AniList request")
+	retryAttempts := fs.Int("retry-attempts", media.PageRetryPolicy.MaxAttempts, "attempts per page before it is marked failed")
+	retryMaxElapsed := fs.Duration("retry-max-elapsed", media.PageRetryPolicy.MaxElapsed, "time budget for retrying a single page")
+	retryRateLimited := fs.Int("retry-rate-limited", media.PageRetryPolicy.MaxRateLimited, "rate limited retries per page on top of --retry-attempts")
+
+	return func() {
+		media.RequestTimeout = *timeout
+		media.PageRetryPolicy.MaxAttempts = *retryAttempts
+		media.PageRetryPolicy.MaxElapsed = *retryMaxElapsed
+		media.PageRetryPolicy.MaxRateLimited = *retryRateLimited
 	}
 }
 
-func updateLowPrioMedia() {
-	if err := runUpdate(func(ctx context.Context, q *database.Queries) ([]int32, error) {
-		return q.QueryLowPrioMedia(ctx)
-	}); err != nil {
-		log.Fatal(err)
+func loadEnv() {
+	required := []string{"PG_HOST", "PG_USER", "PG_PASSWORD", "PG_DATABASE"}
+
+	for _, key := range required {
+		if os.Getenv(key) == "" {
+			if err := godotenv.Load(".env"); err != nil {
+				log.Panicf("No .env file found with err: %s\n", err.Error())
+			}
+		}
 	}
 }
 
-func runUpdate(get mediaListGetter) error {
-	ctx := context.Background()
-
-	connStr := fmt.Sprintf(
-		"host=%s user=%s password=%s database=%s port=5432",
-		os.Getenv("PG_HOST"),
-		os.Getenv("PG_USER"),
-		os.Getenv("PG_PASSWORD"),
-		os.Getenv("PG_DATABASE"),
-	)
+// legacyModes maps the old -mode values onto commands so existing schedules keep working
+var legacyModes = map[string][]string{
+	"all":          {"backfill"},
+	"new":          {"new"},
+	"high":         {"refresh", "--priority", "high"},
+	"low":          {"refresh", "--priority", "low"},
+	"incremental":  {"incremental"},
+	"characters":   {"characters"},
+	"calendar":     {"calendar"},
+	"bench-write":  {"bench-write"},
+	"migrate":      {"migrate"},
+	"retry-failed": {"retry-failed"},
+}
 
-	conn, err := pgx.Connect(ctx, connStr)
-	if err != nil {
-		return err
+// legacyMode rewrites `-mode <name> [args]` into the matching command
+func legacyMode(args []string) []string {
+	if len(args) == 0 {
+		return args
 	}
-	defer conn.Close(ctx)
 
-	q := database.New(conn)
+	var mode string
+	rest := args[1:]
+	switch {
+	case args[0] == "-mode" || args[0] == "--mode":
+		if len(args) < 2 {
+			return args
+		}
+		mode, rest = args[1], args[2:]
+	case strings.HasPrefix(args[0], "-mode=") || strings.HasPrefix(args[0], "--mode="):
+		mode = args[0][strings.Index(args[0], "=")+1:]
+	default:
+		return args
+	}
 
-	mediaList, err := get(ctx, q)
-	if err != nil {
-		return err
+	mapped, ok := legacyModes[mode]
+	if !ok {
+		return args
 	}
-	if mediaList == nil {
-		mediaList = []int32{}
+	fmt.Fprintf(os.Stderr, "-mode is deprecated, run `media_updater %s` instead\n", strings.Join(mapped, " "))
+	return append(append([]string{}, mapped...), rest...)
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
 	}
+	return command{}, false
+}
 
-	fmt.Printf("Updating database with %d media\n", len(mediaList))
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: media_updater <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `media_updater <command> -h` for the flags of a command, flags go before arguments.")
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 error, 2 bad usage, 3 finished with failed pages or media.")
+}
 
-	media.UpdateMedia("https://graphql.anilist.co", media.UpdateFromMediaList, mediaList)
-	return nil
+func commandUsage(w io.Writer, fs *flag.FlagSet, cmd command) {
+	synopsis := "media_updater " + cmd.name + " [flags]"
+	if cmd.args != "" {
+		synopsis += " " + cmd.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", synopsis, cmd.summary)
+
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
 }